@@ -228,6 +228,9 @@ func (this *DubboClient) Send(dubboReq *dubbo.Request) (*dubbo.DubboRsp, error)
 		lager.Logger.Info("Client send timeout.")
 		return nil, &util.BaseError{"timeout"}
 	} else {
+		if result.Rsp != nil {
+			result.Rsp.SetGeneric(dubboReq.IsGeneric())
+		}
 		return result.Rsp, nil
 	}
 }
@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"io"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//BatchEncoder is a struct which encodes many requests into one buffer and writes the buffer to a writer
+//in chunks, which balances the latency of the first frames and the number of writes
+type BatchEncoder struct {
+	//FlushThreshold is the number of buffered bytes from which the buffer is written, 0 writes it once at
+	//the end of every batch. Frames are never split, a chunk may exceed the threshold by one frame
+	FlushThreshold int
+	codec          *DubboCodec
+	writer         io.Writer
+	pending        util.WriteBuffer
+}
+
+//NewBatchEncoder is a function which creates a batch encoder writing the frames of codec to w
+func NewBatchEncoder(codec *DubboCodec, w io.Writer) *BatchEncoder {
+	e := &BatchEncoder{codec: codec, writer: w}
+	e.pending.Init(0)
+	return e
+}
+
+//EncodeDubboReqs is a method which encodes reqs and writes them. When a request can not be encoded the
+//frames before it are written and a FrameError holding its index is returned
+func (e *BatchEncoder) EncodeDubboReqs(reqs []*Request) error {
+	for i, req := range reqs {
+		var buffer util.WriteBuffer
+		buffer.Init(e.codec.EstimateSize(req))
+		if e.codec.EncodeDubboReq(req, &buffer) != 0 {
+			if err := e.Flush(); err != nil {
+				return err
+			}
+			return &FrameError{i, ErrEncodeFailed}
+		}
+		e.pending.WriteBytes(buffer.GetValidData())
+		if e.FlushThreshold > 0 && e.pending.WrittenBytes() >= e.FlushThreshold {
+			if err := e.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return e.Flush()
+}
+
+//Flush is a method which writes the buffered frames
+func (e *BatchEncoder) Flush() error {
+	if e.pending.WrittenBytes() == 0 {
+		return nil
+	}
+	_, err := e.writer.Write(e.pending.GetValidData())
+	e.pending.WriteIndex(0)
+	return err
+}
@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//chunkWriter records the size of every write
+type chunkWriter struct {
+	bytes.Buffer
+	chunks []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestBatchEncoder_FlushThreshold(t *testing.T) {
+	d := &DubboCodec{}
+	frame := len(encodeRequest(t, d, newTestRequest()))
+	reqs := []*Request{newTestRequest(), newTestRequest(), newTestRequest()}
+
+	w := &chunkWriter{}
+	assert.NoError(t, NewBatchEncoder(d, w).EncodeDubboReqs(reqs))
+	assert.Equal(t, []int{3 * frame}, w.chunks)
+
+	w = &chunkWriter{}
+	e := NewBatchEncoder(d, w)
+	e.FlushThreshold = frame + 1
+	assert.NoError(t, e.EncodeDubboReqs(reqs))
+	assert.Equal(t, []int{2 * frame, frame}, w.chunks)
+
+	w = &chunkWriter{}
+	e = NewBatchEncoder(d, w)
+	e.FlushThreshold = frame
+	assert.NoError(t, e.EncodeDubboReqs(reqs))
+	assert.Equal(t, []int{frame, frame, frame}, w.chunks)
+
+	frames := NewFrameReader(&w.Buffer)
+	for range reqs {
+		f, err := frames.ReadFrame()
+		assert.NoError(t, err)
+		decoded, err := decodeRequestFrame(d, append(f.Header, f.Body...))
+		assert.NoError(t, err)
+		assert.Equal(t, "sayHello", decoded.GetMethodName())
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+)
+
+//BudgetPolicy is what a session byte budget does with a frame which does not fit
+type BudgetPolicy int
+
+const (
+	//BudgetBlock makes the frame wait until enough bytes are released, which pauses the reads of session
+	BudgetBlock BudgetPolicy = iota
+	//BudgetReject rejects the frame with ErrByteBudgetExceeded
+	BudgetReject
+)
+
+//SessionByteBudget is a struct which bounds the bytes buffered by the decodes in flight on one session,
+//the bytes of a frame are acquired before its body is read and released once it is decoded
+type SessionByteBudget struct {
+	mtx    sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	used   int
+	policy BudgetPolicy
+}
+
+//NewSessionByteBudget is a function which creates a budget of limit bytes applying policy to the frames
+//which do not fit
+func NewSessionByteBudget(limit int, policy BudgetPolicy) *SessionByteBudget {
+	b := &SessionByteBudget{limit: limit, policy: policy}
+	b.cond = sync.NewCond(&b.mtx)
+	return b
+}
+
+//Acquire is a method which takes n bytes from budget, a frame larger than the whole budget is always
+//rejected since it would never fit
+func (b *SessionByteBudget) Acquire(n int) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if n > b.limit {
+		return ErrByteBudgetExceeded
+	}
+	for b.used+n > b.limit {
+		if b.policy == BudgetReject {
+			return ErrByteBudgetExceeded
+		}
+		b.cond.Wait()
+	}
+	b.used += n
+	return nil
+}
+
+//Release is a method which gives back n bytes acquired from budget
+func (b *SessionByteBudget) Release(n int) {
+	b.mtx.Lock()
+	b.used -= n
+	b.mtx.Unlock()
+	b.cond.Broadcast()
+}
+
+//Used is a method which returns the number of bytes acquired from budget
+func (b *SessionByteBudget) Used() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.used
+}
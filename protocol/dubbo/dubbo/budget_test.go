@@ -0,0 +1,61 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionByteBudget_Reject(t *testing.T) {
+	const bodyLen = 1000
+	b := NewSessionByteBudget(3*bodyLen, BudgetReject)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.Acquire(bodyLen))
+	}
+	assert.Equal(t, ErrByteBudgetExceeded, b.Acquire(bodyLen))
+	assert.Equal(t, 3*bodyLen, b.Used())
+
+	b.Release(bodyLen)
+	assert.NoError(t, b.Acquire(bodyLen))
+	assert.Equal(t, ErrByteBudgetExceeded, b.Acquire(4*bodyLen))
+}
+
+func TestSessionByteBudget_Block(t *testing.T) {
+	const bodyLen = 1000
+	b := NewSessionByteBudget(2*bodyLen, BudgetBlock)
+	assert.NoError(t, b.Acquire(bodyLen))
+	assert.NoError(t, b.Acquire(bodyLen))
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- b.Acquire(bodyLen)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("the third frame should wait for the budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	//releasing the bytes of a decoded frame resumes the reads
+	b.Release(bodyLen)
+	assert.NoError(t, <-acquired)
+	assert.Equal(t, 2*bodyLen, b.Used())
+}
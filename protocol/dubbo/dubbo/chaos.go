@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"math/rand"
+	"time"
+)
+
+//ChaosHook is a struct which injects faults into the decode of frame bodies for resilience testing, such as
+//of the retries and circuit breakers of the proxy. Faults are only injected by the codecs it is set on
+type ChaosHook struct {
+	//Fraction is the fraction of frames affected, between 0 and 1
+	Fraction float64
+	//Delay is added to the decode of the affected frames
+	Delay time.Duration
+	//Err fails the decode of the affected frames, such as with ErrChaosInjected, nil only delays them
+	Err error
+	//Rand returns the number in [0, 1) which decides whether a frame is affected, nil uses math/rand
+	Rand func() float64
+}
+
+//inject delays the decode of a frame, or fails it, when the frame is one of the affected fraction
+func (h *ChaosHook) inject() error {
+	random := rand.Float64
+	if h.Rand != nil {
+		random = h.Rand
+	}
+	if random() >= h.Fraction {
+		return nil
+	}
+	if h.Delay > 0 {
+		time.Sleep(h.Delay)
+	}
+	return h.Err
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaosHook_FailFraction(t *testing.T) {
+	d := &DubboCodec{ChaosHook: &ChaosHook{
+		Fraction: 0.5,
+		Err:      ErrChaosInjected,
+		Rand:     rand.New(rand.NewSource(1)).Float64,
+	}}
+	frame := encodeRequest(t, d, newTestRequest())
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		decoded, ret := decodeRequest(d, frame)
+		if ret != 0 {
+			assert.Equal(t, ErrChaosInjected.Error(), decoded.GetData())
+			assert.True(t, decoded.IsBroken())
+			assert.Equal(t, ErrChaosInjected.Status, decoded.GetStatus())
+			failed++
+		}
+	}
+	assert.InDelta(t, 500, failed, 50)
+
+	_, ret := decodeRequest(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+
+	d.ChaosHook.Fraction = 1
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	_, err := d.DecodeRequest(frame[:HeaderLength], &body)
+	assert.Equal(t, ErrChaosInjected, err)
+}
+
+func TestChaosHook_Delay(t *testing.T) {
+	d := &DubboCodec{ChaosHook: &ChaosHook{Fraction: 1, Delay: 20 * time.Millisecond}}
+	start := time.Now()
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
@@ -18,6 +18,12 @@
 package dubbo
 
 import (
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/go-mesh/mesher/protocol/dubbo/utils"
 )
 
@@ -32,6 +38,7 @@ const (
 	FlagEvent         = byte(0x20)
 	SerializationMask = byte(0x1f)
 	HeartBeatEvent    = ""
+	ReadOnlyEvent     = "R"
 )
 
 //Constants for dubbo attributes
@@ -44,6 +51,26 @@ const (
 	CommaSeparator     string = ","
 	FileSeparator      string = "/"
 	SemicolonSeparator string = ";"
+	GenericInvoke      string = "$invoke"
+	GenericInvokeAsync string = "$invokeAsync"
+	ViaKey             string = "via"
+	SerializationKey   string = "serialization"
+	CategoryKey        string = "category"
+	TimeoutKey         string = "timeout"
+	UpgradeKey         string = "upgrade"
+	SideKey            string = "side"
+	MethodsKey         string = "methods"
+	GroupKey           string = "group"
+	KeepAliveKey       string = "keepalive"
+	ExecutorKey        string = "executor"
+	ThreadPoolKey      string = "threadpool"
+	PidKey             string = "pid"
+	StickyKey          string = "sticky"
+	ValidationKey      string = "validation"
+	ValidationGroupKey string = "validation.group"
+	LoadBalanceKey     string = "loadbalance"
+	ConsumerSide       string = "consumer"
+	ProviderSide       string = "provider"
 )
 
 //Constants
@@ -52,15 +79,96 @@ const (
 	NeedMore             = -1
 	InvalidFragement     = -2
 	InvalidSerialization = -3
+	UnsupportedProtocol  = -4
+	//SerializationNotAllowed means the serialization is not in the allowed list of codec
+	SerializationNotAllowed = -5
+	//QosCommandLine means the data is a qos command line, which should be parsed by ParseQosCommand
+	QosCommandLine = -6
+	//InvalidBodyLength means the body length of the header is negative, the body can not be skipped
+	InvalidBodyLength = -7
 )
 
+//HTTP2Preface is the connection preface of http/2, which dubbo 3 triple protocol is built on
+const HTTP2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
 //serialise type
 const (
-	Hessian2 = byte(2)
+	Hessian2      = byte(2)
+	NativeJava    = byte(3)
+	CompactedJava = byte(4)
+	FastJSON      = byte(6)
 )
 
+//serializationNames maps the names used by the serialization attachment to serialization ids
+var serializationNames = map[string]byte{
+	"hessian2":      Hessian2,
+	"java":          NativeJava,
+	"compactedjava": CompactedJava,
+	"fastjson":      FastJSON,
+}
+
+func init() {
+	util.RegisterSerializer(Hessian2, util.Hessian2Serializer{})
+}
+
 //DubboCodec is a struct
 type DubboCodec struct {
+	//Strict enables extra validation of the decoded frames
+	Strict bool
+	//MaxDepth limits how deep the decoded objects may nest, 0 means no limit
+	MaxDepth int
+	//MaxArgumentSize limits the encoded size of every argument, 0 means no limit
+	MaxArgumentSize int
+	//MaxStringLength limits the length in characters of the strings heading a request body,
+	//such as the path and the method, 0 means no limit
+	MaxStringLength int
+	//ExpectedDescriptor returns the parameter descriptor of the method resolved for path,
+	//it enables boxing and unboxing of the decoded arguments, nil disables coercion
+	ExpectedDescriptor func(path string, method string) (string, bool)
+	//Interner shares the backing of repeated decoded strings, nil disables interning
+	Interner *util.StringInterner
+	//AllowedSerializations lists the serialization ids accepted on the connection, empty means all
+	AllowedSerializations []byte
+	//SlowDecodeThreshold is the decode time of a request body above which it is reported, 0 disables it
+	SlowDecodeThreshold time.Duration
+	//OnSlowDecode receives the slow decodes, nil logs them as warnings
+	OnSlowDecode func(SlowDecode)
+	//PreserveTransportStatus keeps the status of responses carrying an exception and sets the exception of
+	//response instead of its value, false overwrites the status with ServiceError as before
+	PreserveTransportStatus bool
+	//DecodeConcurrency limits the bodies decoded at the same time, nil means no limit
+	DecodeConcurrency *DecodeConcurrency
+	//AllowedAttachments lists the attachment keys kept by decode besides the keys of the dubbo protocol,
+	//other keys are dropped. Empty preserves all keys verbatim
+	AllowedAttachments []string
+	//DubboVersionRewriter returns the dubbo version written for the version of a forwarded request, such as
+	//2.0.2 for 2.0.0 when bridging to a version-strict provider, nil writes the version unchanged
+	DubboVersionRewriter func(in string) string
+	//CaptureRaw keeps a copy of the encoded arguments of requests and of the encoded value of responses
+	//besides their decoded form, so they can be both routed on and forwarded verbatim
+	CaptureRaw bool
+	//OnMethodDecoded is called with the interface and the method of every request once they are decoded,
+	//before the arguments, such as to count the calls of every method. nil does nothing
+	OnMethodDecoded func(interfaceName, method string)
+	//OmitAttachments writes an empty attachment map for every request, like the requests set to omit them,
+	//the path and versions are still sent in the body head
+	OmitAttachments bool
+	//ExpectedInterface is the only interface whose requests are decoded, such as for a single-service sidecar.
+	//Requests of other interfaces are rejected with ServiceNotFound before their arguments, empty accepts all
+	ExpectedInterface string
+	//ChaosHook injects delays and decode errors into a fraction of the frames for resilience testing,
+	//nil injects nothing
+	ChaosHook *ChaosHook
+	//KeepPartialRequests keeps the arguments decoded before the argument which fails to decode in the broken
+	//request, besides its interface and method, so the failure can be handled per method
+	KeepPartialRequests bool
+	//MethodArity is the expected number of arguments of methods by name, requests of these methods declaring
+	//another number of arguments are rejected with BadRequest before their arguments are decoded
+	MethodArity map[string]int
+	//JavaSerialization decodes the bodies of the native and the compacted java serializations on a best effort
+	//basis, for routing. Their objects are decoded into util.JavaSerialObject, which is not the object the
+	//provider expects once encoded again, so such requests must not be forwarded re-encoded. false rejects them
+	JavaSerialization bool
 }
 
 //GetContentTypeID is a method which returns content type id
@@ -92,23 +200,11 @@ func (p *DubboCodec) EncodeDubboRsp(rsp *DubboRsp, buffer *util.WriteBuffer) int
 			buffer.WriteObject(ret)
 		} else {
 			//encodeResponseData
-			except := rsp.GetException()
-			if except == nil {
-				ret := rsp.GetValue()
-				if ret == nil {
-					buffer.WriteByte(ResponseNullValue)
-				} else {
-					buffer.WriteByte(ResponseValue)
-					buffer.WriteObject(ret)
-				}
-			} else {
-				buffer.WriteByte(ResponseWithException)
-				buffer.WriteObject(except)
-			}
+			p.encodeResult(rsp, buffer)
 		}
 	} else {
 		if rsp.GetErrorMsg() == "" {
-			buffer.WriteByte(ResponseNullValue)
+			buffer.WriteObject(nil)
 		} else {
 			buffer.WriteObject(rsp.GetErrorMsg())
 		}
@@ -125,11 +221,43 @@ func (p *DubboCodec) EncodeDubboRsp(rsp *DubboRsp, buffer *util.WriteBuffer) int
 	return 0
 }
 
+//encodeResult writes the value or exception of rsp, followed by its attachments if the peer supports them
+func (p *DubboCodec) encodeResult(rsp *DubboRsp, buffer *util.WriteBuffer) {
+	var offset byte
+	attachments := rsp.GetAttachments()
+	if len(attachments) > 0 && isSupportResponseAttachment(rsp.GetVersion()) {
+		offset = ResponseWithExceptionWithAttachments
+	}
+	except := rsp.GetException()
+	if except == nil {
+		ret := rsp.GetValue()
+		if ret == nil {
+			buffer.WriteByte(ResponseNullValue + offset)
+		} else {
+			buffer.WriteByte(ResponseValue + offset)
+			buffer.WriteObject(ret)
+		}
+	} else {
+		buffer.WriteByte(ResponseWithException + offset)
+		buffer.WriteObject(except)
+	}
+	if offset != 0 {
+		buffer.WriteObject(attachments)
+	}
+}
+
 //DecodeDubboRsqHead is a method which decodes dubbo response header
 func (p *DubboCodec) DecodeDubboRsqHead(rsp *DubboRsp, header []byte, bodyLen *int) int {
+	_, ret := p.decodeRspHead(rsp, header, bodyLen, nil)
+	return ret
+}
 
+//decodeRspHead decodes a response header and returns the serializer of its body. The serialization is checked
+//against the allowed serializations of codec, known is returned as its serializer instead of looking it up
+//when it is not nil
+func (p *DubboCodec) decodeRspHead(rsp *DubboRsp, header []byte, bodyLen *int, known util.BodySerializer) (util.BodySerializer, int) {
 	if header[0] != MagicHigh || header[1] != MagicLow {
-		return InvalidFragement
+		return nil, InvalidFragement
 	}
 	//读取请求ID
 	var id int64 = util.Bytes2long(header, 4)
@@ -140,21 +268,195 @@ func (p *DubboCodec) DecodeDubboRsqHead(rsp *DubboRsp, header []byte, bodyLen *i
 		rsp.SetEvent(true)
 	}
 	proto := byte(flag & SerializationMask)
-
-	if proto != Hessian2 { //当前只支持hessian2编码
-		return InvalidSerialization
+	if p.CheckSerialization(proto) != nil {
+		return nil, SerializationNotAllowed
+	}
+	s := known
+	if s == nil {
+		var ok bool
+		if s, ok = p.bodySerializer(proto); !ok {
+			return nil, InvalidSerialization
+		}
 	}
+	rsp.SetSerialization(proto)
 	status := header[3]
 	rsp.SetStatus(status)
 	//读取长度
 	*bodyLen = int(util.Bytes2int(header, 12))
-	return Success
+	if *bodyLen < 0 {
+		return nil, InvalidBodyLength
+	}
+	return s, Success
+}
+
+//bodySerializer returns the serializer reading the bodies of the serialization id, 0 means hessian2. The java
+//serializations are read when codec enables them, otherwise only the registered serializers able to read whole
+//bodies are returned
+func (p *DubboCodec) bodySerializer(id byte) (util.BodySerializer, bool) {
+	switch id {
+	case 0:
+		id = Hessian2
+	case NativeJava, CompactedJava:
+		if p.JavaSerialization {
+			return util.JavaSerializer{Compacted: id == CompactedJava}, true
+		}
+	}
+	s, ok := util.GetSerializer(id)
+	if !ok {
+		return nil, false
+	}
+	bs, ok := s.(util.BodySerializer)
+	return bs, ok
+}
+
+//requestSerializer returns the serializer reading the body of req, req is broken with ErrUnknownSerialization
+//when there is none
+func (p *DubboCodec) requestSerializer(req *Request) (util.BodySerializer, bool) {
+	s, ok := p.bodySerializer(req.GetSerialization())
+	if !ok {
+		req.SetBroken(true)
+		req.status = ErrUnknownSerialization.Status
+		req.SetData(ErrUnknownSerialization.Error())
+	}
+	return s, ok
+}
+
+//CheckSerialization returns ErrSerializationNotAllowed if the serialization id is not allowed by codec
+func (p *DubboCodec) CheckSerialization(id byte) error {
+	if len(p.AllowedSerializations) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedSerializations {
+		if allowed == id {
+			return nil
+		}
+	}
+	return ErrSerializationNotAllowed
+}
+
+//DecodeResponse is a method which decodes the header and body of a response into a result.
+//Errors reported by the provider are kept in the result, only decode failures return an error
+func (p *DubboCodec) DecodeResponse(head []byte, body *util.ReadBuffer) (*ResponseResult, error) {
+	result, _, err := p.decodeResponse(head, body, nil)
+	return result, err
+}
+
+//decodeResponse decodes a response and returns the serializer its body was read with, known is used as the
+//serializer of its serialization instead of looking it up when it is not nil
+func (p *DubboCodec) decodeResponse(head []byte, body *util.ReadBuffer, known util.BodySerializer) (*ResponseResult, util.BodySerializer, error) {
+	if len(head) < HeaderLength {
+		return nil, nil, ErrInvalidHeader
+	}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	bodyLen := 0
+	s, ret := p.decodeRspHead(rsp, head, &bodyLen, known)
+	if ret != Success {
+		return nil, nil, headerError(ret)
+	}
+	if p.decodeRspBody(s, body, rsp) != 0 {
+		return nil, nil, &CodecError{rsp.GetStatus(), rsp.GetErrorMsg()}
+	}
+	result := &ResponseResult{
+		ID:          rsp.GetID(),
+		Status:      rsp.GetStatus(),
+		ErrorMsg:    rsp.GetErrorMsg(),
+		Attachments: rsp.GetAttachments(),
+	}
+	if rsp.GetException() != nil {
+		result.Exception = rsp.GetException()
+	} else if rsp.GetStatus() == ServiceError {
+		result.Exception = rsp.GetValue()
+	} else {
+		result.Value = rsp.GetValue()
+	}
+	return result, s, nil
+}
+
+//DecodeRequest is a method which decodes the header and body of a request
+func (p *DubboCodec) DecodeRequest(head []byte, body *util.ReadBuffer) (*Request, error) {
+	req := new(Request)
+	bodyLen := 0
+	if ret := p.DecodeDubboReqHead(req, head, &bodyLen); ret != Success {
+		return nil, headerError(ret)
+	}
+	return req, p.decodeRequestBody(req, body)
+}
+
+//DecodeRequestInto is a method which resets req and decodes the header and body of a request into it,
+//so that requests can be pooled
+func (p *DubboCodec) DecodeRequestInto(req *Request, head []byte, body *util.ReadBuffer) error {
+	req.Reset()
+	bodyLen := 0
+	if ret := p.DecodeDubboReqHead(req, head, &bodyLen); ret != Success {
+		return headerError(ret)
+	}
+	return p.decodeRequestBody(req, body)
+}
+
+//decodeRequestBody decodes the body of req and converts the failure into an error with the status of req
+func (p *DubboCodec) decodeRequestBody(req *Request, body *util.ReadBuffer) error {
+	if p.DecodeDubboReqBody(req, body) != 0 {
+		return req.DecodeError()
+	}
+	return nil
+}
+
+//acquireDecode waits until the decode concurrency of codec allows one more decode, and injects the faults of
+//the chaos hook of codec. The returned function must be called when the decode is done
+func (p *DubboCodec) acquireDecode() (func(), error) {
+	release := func() {}
+	if p.DecodeConcurrency != nil {
+		if err := p.DecodeConcurrency.acquire(); err != nil {
+			return nil, err
+		}
+		release = p.DecodeConcurrency.release
+	}
+	if p.ChaosHook != nil {
+		if err := p.ChaosHook.inject(); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+//prepareBody applies the decode limits of codec to the body buffer
+func (p *DubboCodec) prepareBody(buffer *util.ReadBuffer) {
+	if p.MaxDepth > 0 {
+		buffer.SetMaxDepth(p.MaxDepth)
+	}
+	if p.MaxStringLength > 0 {
+		buffer.SetMaxStringLength(p.MaxStringLength)
+	}
+	if p.Interner != nil {
+		buffer.SetInterner(p.Interner)
+	}
 }
 
 //DecodeDubboRspBody is a method which decodes dubbo response body
 func (p *DubboCodec) DecodeDubboRspBody(buffer *util.ReadBuffer, rsp *DubboRsp) int {
+	s, ok := p.bodySerializer(rsp.GetSerialization())
+	if !ok {
+		rsp.SetStatus(ErrUnknownSerialization.Status)
+		rsp.SetErrorMsg(ErrUnknownSerialization.Error())
+		return -1
+	}
+	return p.decodeRspBody(s, buffer, rsp)
+}
+
+//decodeRspBody decodes a response body with the serializer s
+func (p *DubboCodec) decodeRspBody(s util.BodySerializer, buffer *util.ReadBuffer, rsp *DubboRsp) int {
 	var obj interface{}
 	var err error
+	release, err := p.acquireDecode()
+	if err != nil {
+		rsp.SetStatus(errorStatus(err))
+		rsp.SetErrorMsg(err.Error())
+		return -1
+	}
+	defer release()
+	p.prepareBody(buffer)
 
 	if rsp.IsHeartbeat() {
 		rsp.SetValue(HeartBeatEvent)
@@ -163,7 +465,7 @@ func (p *DubboCodec) DecodeDubboRspBody(buffer *util.ReadBuffer, rsp *DubboRsp)
 	if rsp.GetStatus() == Ok {
 		if rsp.IsHeartbeat() && (HeartBeatEvent == rsp.GetValue()) {
 			//decodeHeartbeatData
-			obj, err = buffer.ReadObject()
+			obj, err = s.ReadObject(buffer)
 			if err != nil {
 				rsp.SetStatus(ServerError)
 				rsp.SetErrorMsg(err.Error())
@@ -171,7 +473,7 @@ func (p *DubboCodec) DecodeDubboRspBody(buffer *util.ReadBuffer, rsp *DubboRsp)
 			}
 		} else if rsp.mEvent {
 			//decodeEventData
-			obj, err = buffer.ReadObject()
+			obj, err = s.ReadObject(buffer)
 			if err != nil {
 				rsp.SetStatus(ServerError)
 				rsp.SetErrorMsg(err.Error())
@@ -179,47 +481,154 @@ func (p *DubboCodec) DecodeDubboRspBody(buffer *util.ReadBuffer, rsp *DubboRsp)
 			}
 		} else {
 			//decodeResult
-			var valueType byte = buffer.ReadByte()
-			switch valueType {
-			case ResponseNullValue:
-				//do nothing
-				rsp.SetValue(nil)
-				return 0
-			case ResponseValue:
-				obj, err = buffer.ReadObject()
-				if err != nil {
-					rsp.SetStatus(ServerError)
-					rsp.SetErrorMsg(err.Error())
-					return -1
-				}
-			case ResponseWithException:
-				//readObject,设置异常
-				rsp.SetStatus(ServiceError)
-				obj, err = buffer.ReadObject()
-				if err != nil {
-					rsp.SetStatus(ServerError)
-					rsp.SetErrorMsg(err.Error())
-					return 0
-				}
-			}
+			return p.decodeCheckedResult(s, buffer, rsp)
 		}
 		rsp.SetValue(obj)
 	} else {
-		obj, err = buffer.ReadObject()
+		decodeErrorMsg(s, buffer, rsp)
+	}
+
+	return 0
+}
+
+//decodeErrorMsg reads the error message of a response whose status is not ok, a message which is not a string
+//is reported as an unknown error
+func decodeErrorMsg(s util.BodySerializer, buffer *util.ReadBuffer, rsp *DubboRsp) {
+	msg, err := s.ReadUTF(buffer)
+	switch {
+	case err == util.ErrNotString:
+		rsp.SetErrorMsg("unknown error")
+	case err != nil:
+		rsp.SetErrorMsg(err.Error())
+	case msg != "":
+		rsp.SetErrorMsg(msg)
+	}
+}
+
+//decodeResult reads the value of a normal response according to its value type
+func (p *DubboCodec) decodeResult(s util.BodySerializer, buffer *util.ReadBuffer, rsp *DubboRsp) int {
+	valueType, err := s.ReadFlag(buffer)
+	if err != nil {
+		rsp.SetStatus(ErrMissingValueType.Status)
+		rsp.SetErrorMsg(ErrMissingValueType.Error())
+		return -1
+	}
+	withAttachments := valueType >= ResponseWithExceptionWithAttachments && valueType <= ResponseNullValueWithAttachments
+	if withAttachments {
+		valueType -= ResponseWithExceptionWithAttachments
+	}
+	start := buffer.Consumed()
+	ret := p.decodeValue(s, buffer, rsp, valueType)
+	rsp.SetRawValue(p.rawSpan(buffer, start))
+	if ret == 0 && withAttachments {
+		attachments, err := s.ReadMap(buffer)
 		if err != nil {
+			rsp.SetStatus(BadResponse)
 			rsp.SetErrorMsg(err.Error())
-		} else {
-			if s, ok := obj.(string); !ok {
-				rsp.SetErrorMsg("unknown error")
-			} else {
-				rsp.SetErrorMsg(s)
-			}
+			return -1
 		}
+		rsp.SetAttachments(p.filterAttachments(attachments))
+	}
+	return ret
+}
+
+//decodeCheckedResult reads the result of a normal response and checks in strict mode that it fills the body
+func (p *DubboCodec) decodeCheckedResult(s util.BodySerializer, buffer *util.ReadBuffer, rsp *DubboRsp) int {
+	ret := p.decodeResult(s, buffer, rsp)
+	if ret != 0 {
+		return ret
 	}
+	if err := p.checkConsumed(buffer, ErrResponseBodyLength); err != nil {
+		rsp.SetStatus(ErrResponseBodyLength.Status)
+		rsp.SetErrorMsg(err.Error())
+		return -1
+	}
+	return 0
+}
 
+//decodeValue reads the value or exception of the value type into rsp
+func (p *DubboCodec) decodeValue(s util.BodySerializer, buffer *util.ReadBuffer, rsp *DubboRsp, valueType byte) int {
+	var obj interface{}
+	var err error
+	switch valueType {
+	case ResponseNullValue:
+		//do nothing
+		rsp.SetValue(nil)
+		return 0
+	case ResponseValue:
+		obj, err = s.ReadObject(buffer)
+		if err != nil {
+			rsp.SetStatus(ServerError)
+			rsp.SetErrorMsg(err.Error())
+			return -1
+		}
+	case ResponseWithException:
+		//readObject,设置异常
+		if !p.PreserveTransportStatus {
+			rsp.SetStatus(ServiceError)
+		}
+		obj, err = s.ReadObject(buffer)
+		if err != nil {
+			rsp.SetStatus(ServerError)
+			rsp.SetErrorMsg(err.Error())
+			return 0
+		}
+		if p.PreserveTransportStatus {
+			rsp.SetException(obj)
+			return 0
+		}
+	default:
+		if p.Strict {
+			rsp.SetStatus(ErrUnknownValueType.Status)
+			rsp.SetErrorMsg(ErrUnknownValueType.Error())
+			return -1
+		}
+	}
+	rsp.SetValue(obj)
 	return 0
 }
 
+//isSupportResponseAttachment reports whether a peer of the dubbo version can decode response attachments,
+//which are supported since 2.0.2 except the releases 2.0.10 to 2.6.2 numbered after the protocol
+func isSupportResponseAttachment(version string) bool {
+	v := intVersion(version)
+	if v >= 2001000 && v < 2060300 {
+		return false
+	}
+	return v >= 2000200
+}
+
+//intVersion converts a version such as "2.0.2" to a comparable number such as 2000200
+func intVersion(version string) int {
+	v := 0
+	parts := strings.Split(version, ".")
+	for i := 0; i < 4; i++ {
+		v *= 100
+		if i < len(parts) {
+			n, _ := strconv.Atoi(parts[i])
+			v += n
+		}
+	}
+	return v
+}
+
+//EstimateSize is a method which estimates the size of the frame req is encoded in,
+//it can be used as the capacity of the write buffer
+func (p *DubboCodec) EstimateSize(req *Request) int {
+	size := HeaderLength
+	if req.IsEvent() {
+		return size + util.EstimateSize(req.GetData())
+	}
+	size += len(DubboVersion) + len(req.GetAttachment(PathKey, "")) + len(req.GetMethodName()) + 3*4
+	for _, arg := range req.GetArguments() {
+		size += len(arg.GetJavaType()) + util.EstimateSize(arg.GetValue())
+	}
+	if p.omitsAttachments(req) {
+		return size + 2
+	}
+	return size + util.EstimateSize(req.GetAttachments())
+}
+
 //EncodeDubboReq is a method which encodes dubbo request
 func (p *DubboCodec) EncodeDubboReq(req *Request, buffer *util.WriteBuffer) int {
 	// set Magic number.
@@ -245,8 +654,37 @@ func (p *DubboCodec) EncodeDubboReq(req *Request, buffer *util.WriteBuffer) int
 		return -1
 	}
 
+	var err error
+	if req.IsEvent() {
+		err = p.encodeEventData(req, buffer)
+	} else {
+		err = p.encodeRequestData(req, buffer)
+	}
+	if err != nil {
+		return -1
+	}
+
+	len := buffer.WrittenBytes() - HeaderLength
+	util.Int2bytes(len, header, 12)
+	buffer.WriteIndex(0)
+	buffer.WriteBytes(header)
+	buffer.WriteIndex(HeaderLength + len)
+
+	return 0
+}
+
+//encodeEventData writes the data of heartbeat and event requests
+func (p *DubboCodec) encodeEventData(req *Request, buffer *util.WriteBuffer) error {
+	if req.IsHeartbeat() {
+		return buffer.WriteObject(nil)
+	}
+	return buffer.WriteObject(req.GetData())
+}
+
+func (p *DubboCodec) encodeRequestData(req *Request, buffer *util.WriteBuffer) error {
 	//写入dubbo version
-	buffer.WriteObject(req.GetAttachment(DubboVersionKey, DubboVersion))
+	version := p.dubboVersionOf(req)
+	buffer.WriteObject(version)
 	//写入path key
 	buffer.WriteObject(req.GetAttachment(PathKey, ""))
 	//写入接口version key
@@ -262,53 +700,143 @@ func (p *DubboCodec) EncodeDubboReq(req *Request, buffer *util.WriteBuffer) int
 	if argObjs != nil {
 		size := len(argObjs)
 		for i := 0; i < size; i++ {
-			err = buffer.WriteObject(argObjs[i].GetValue())
+			err = p.writeArgument(buffer, &argObjs[i])
 			if err != nil {
-				return -1
+				return err
 			}
 		}
 	}
 	//写入attatchmanets
-	buffer.WriteObject(req.GetAttachments())
+	return buffer.WriteObject(p.attachmentsOf(req, version))
+}
 
-	len := buffer.WrittenBytes() - HeaderLength
-	util.Int2bytes(len, header, 12)
-	buffer.WriteIndex(0)
-	buffer.WriteBytes(header)
-	buffer.WriteIndex(HeaderLength + len)
+//dubboVersionOf returns the dubbo version written for req, rewritten by the version rewriter of codec
+func (p *DubboCodec) dubboVersionOf(req *Request) string {
+	version := req.GetAttachment(DubboVersionKey, DubboVersion)
+	if p.DubboVersionRewriter != nil {
+		version = p.DubboVersionRewriter(version)
+	}
+	return version
+}
 
+//omitsAttachments reports whether an empty attachment map is written for req
+func (p *DubboCodec) omitsAttachments(req *Request) bool {
+	return p.OmitAttachments || req.OmitsAttachments()
+}
+
+//attachmentsOf returns the attachments written for req, a copy holding version when it was rewritten
+func (p *DubboCodec) attachmentsOf(req *Request, version string) map[string]string {
+	if p.omitsAttachments(req) {
+		return map[string]string{}
+	}
+	attachments := req.GetAttachments()
+	if in, ok := attachments[DubboVersionKey]; !ok || in == version {
+		return attachments
+	}
+	rewritten := make(map[string]string, len(attachments))
+	for k, v := range attachments {
+		rewritten[k] = v
+	}
+	rewritten[DubboVersionKey] = version
+	return rewritten
+}
+
+//serializerOf returns the serializer of an argument, nil means the one of connection
+func (p *DubboCodec) serializerOf(arg *util.Argument) (util.Serializer, error) {
+	id := arg.GetSerialization()
+	if id == 0 || id == p.GetContentTypeID() {
+		return nil, nil
+	}
+	s, ok := util.GetSerializer(id)
+	if !ok {
+		return nil, ErrUnknownSerialization
+	}
+	return s, nil
+}
+
+func (p *DubboCodec) writeArgument(buffer *util.WriteBuffer, arg *util.Argument) error {
+	s, err := p.serializerOf(arg)
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		return s.WriteObject(buffer, arg.GetValue())
+	}
+	return buffer.WriteObject(util.ConvertToJavaType(arg.GetJavaType(), arg.GetValue()))
+}
+
+//readArgument reads an argument with its serializer, or with the serializer of the frame. The size of the
+//arguments of hessian2 frames is checked before they are read
+func (p *DubboCodec) readArgument(bodyBuf *util.ReadBuffer, arg *util.Argument, frame util.BodySerializer) (interface{}, error) {
+	s, err := p.serializerOf(arg)
+	if err != nil {
+		return nil, err
+	}
+	if _, hessian := frame.(util.Hessian2Serializer); hessian && s == nil && p.MaxArgumentSize > 0 {
+		if err := bodyBuf.CheckNextSize(p.MaxArgumentSize); err == util.ErrValueTooLarge {
+			return nil, ErrArgumentTooLarge
+		}
+	}
+	if s == nil {
+		s = frame
+	}
+	val, err := s.ReadObject(bodyBuf)
+	if err != nil {
+		return nil, err
+	}
+	return util.ConvertByJavaType(arg.GetJavaType(), val)
+}
+
+//decodeEventData reads the data of heartbeat and event requests, null data means heartbeat
+func (p *DubboCodec) decodeEventData(req *Request, s util.BodySerializer, bodyBuf *util.ReadBuffer) int {
+	obj, err := s.ReadObject(bodyBuf)
+	if err != nil {
+		req.SetData(err.Error())
+		req.SetBroken(true)
+		return -1
+	}
+	if obj != nil {
+		req.SetData(obj)
+	}
 	return 0
 }
 
+//readBodyHead reads the strings heading a request body into req and returns the parameter descriptor
+func readBodyHead(req *Request, s util.BodySerializer, bodyBuf *util.ReadBuffer) (string, error) {
+	var head [5]string
+	for i := range head {
+		v, err := s.ReadUTF(bodyBuf)
+		if err != nil {
+			return "", err
+		}
+		head[i] = v
+	}
+	req.SetAttachment(DubboVersionKey, head[0])
+	req.SetAttachment(PathKey, head[1])
+	req.SetAttachment(VersionKey, head[2])
+	req.SetVersion(head[2])
+	req.SetMethodName(head[3])
+	return head[4], nil
+}
+
 //DecodeDubboReqBodyForRegstry is a method which decodes dubbo request body from registry
 func (p *DubboCodec) DecodeDubboReqBodyForRegstry(req *Request, bodyBuf *util.ReadBuffer) int {
 	var obj interface{}
-	var err error
-	if req.IsHeartbeat() {
-		//decodeHeartbeatData
-		obj, err = bodyBuf.ReadObject()
+	p.prepareBody(bodyBuf)
+	s, ok := p.requestSerializer(req)
+	if !ok {
+		return -1
+	}
+	if req.IsEvent() {
+		return p.decodeEventData(req, s, bodyBuf)
+	} else {
+		typeDesc, err := readBodyHead(req, s, bodyBuf)
 		if err != nil {
-			req.SetData(err.Error())
 			req.SetBroken(true)
-			return -1
-		}
-	} else if req.IsEvent() {
-		//decodeEventData
-		obj, err = bodyBuf.ReadObject()
-		if err != nil {
 			req.SetData(err.Error())
-			req.SetBroken(true)
 			return -1
 		}
-	} else {
-		req.SetAttachment(DubboVersionKey, bodyBuf.ReadString())
-		req.SetAttachment(PathKey, bodyBuf.ReadString())
-		req.SetAttachment(VersionKey, bodyBuf.ReadString())
-		req.SetVersion(req.GetAttachment(VersionKey, ""))
-		req.SetMethodName(bodyBuf.ReadString())
-
 		//解析参数
-		typeDesc := string(bodyBuf.ReadString())
 		agrsArry := util.TypeDesToArgsObjArry(typeDesc)
 		if typeDesc == "" {
 			agrsArry = nil
@@ -318,7 +846,7 @@ func (p *DubboCodec) DecodeDubboReqBodyForRegstry(req *Request, bodyBuf *util.Re
 				size = 1
 			}
 			for i := 0; i < size; i++ {
-				val, err := bodyBuf.ReadObject()
+				val, err := p.readArgument(bodyBuf, &agrsArry[i], s)
 				if err != nil {
 					req.SetBroken(true)
 					req.SetData(err.Error())
@@ -328,6 +856,9 @@ func (p *DubboCodec) DecodeDubboReqBodyForRegstry(req *Request, bodyBuf *util.Re
 				}
 			}
 			req.SetArguments(agrsArry)
+			if size > 0 {
+				req.SetRegistryCategory(registryCategory(agrsArry[0].GetValue()))
+			}
 		}
 
 		if err == nil {
@@ -347,39 +878,48 @@ func (p *DubboCodec) DecodeDubboReqBodyForRegstry(req *Request, bodyBuf *util.Re
 //DecodeDubboReqBody is a method which decodes dobbo request body
 func (p *DubboCodec) DecodeDubboReqBody(req *Request, bodyBuf *util.ReadBuffer) int {
 	var obj interface{}
-	var err error
-	if req.IsHeartbeat() {
-		//decodeHeartbeatData
-		obj, err = bodyBuf.ReadObject()
-		if err != nil {
-			req.SetData(err.Error())
-			req.SetBroken(true)
-			return -1
+	release, err := p.acquireDecode()
+	if err != nil {
+		req.SetBroken(true)
+		req.status = errorStatus(err)
+		req.SetData(err.Error())
+		return -1
+	}
+	defer release()
+	if p.SlowDecodeThreshold > 0 {
+		defer p.checkSlowDecode(req, bodyBuf, time.Now())
+	}
+	p.prepareBody(bodyBuf)
+	s, ok := p.requestSerializer(req)
+	if !ok {
+		return -1
+	}
+	if req.IsEvent() {
+		return p.decodeEventData(req, s, bodyBuf)
+	} else {
+		typeDesc, err := readBodyHead(req, s, bodyBuf)
+		if err == nil {
+			err = p.checkBodyHead(req, typeDesc)
 		}
-	} else if req.IsEvent() {
-		//decodeEventData
-		obj, err = bodyBuf.ReadObject()
 		if err != nil {
-			req.SetData(err.Error())
 			req.SetBroken(true)
+			req.SetData(err.Error())
 			return -1
 		}
-	} else {
-		req.SetAttachment(DubboVersionKey, bodyBuf.ReadString())
-		req.SetAttachment(PathKey, bodyBuf.ReadString())
-		req.SetAttachment(VersionKey, bodyBuf.ReadString())
-		req.SetVersion(req.GetAttachment(VersionKey, ""))
-		req.SetMethodName(bodyBuf.ReadString())
+		if p.OnMethodDecoded != nil {
+			p.OnMethodDecoded(req.GetAttachment(PathKey, ""), req.GetMethodName())
+		}
 		//解析参数
-		typeDesc := string(bodyBuf.ReadString())
 		agrsArry := util.TypeDesToArgsObjArry(typeDesc)
 		if typeDesc == "" {
 			agrsArry = nil
 		} else {
 			size := len(agrsArry)
+			start := bodyBuf.Consumed()
 			for i := 0; i < size; i++ {
-				val, err := bodyBuf.ReadObject()
+				val, err := p.readArgument(bodyBuf, &agrsArry[i], s)
 				if err != nil {
+					p.keepPartial(req, agrsArry[:i])
 					req.SetBroken(true)
 					req.SetData(err.Error())
 					return -1
@@ -387,11 +927,16 @@ func (p *DubboCodec) DecodeDubboReqBody(req *Request, bodyBuf *util.ReadBuffer)
 					agrsArry[i].SetValue(val)
 				}
 			}
+			req.SetRawArguments(p.rawSpan(bodyBuf, start))
+			p.coerceArguments(req, agrsArry)
 			req.SetArguments(agrsArry)
 		}
-		attatchments, err := bodyBuf.ReadMap()
+		attatchments, err := p.readAttachments(req, s, bodyBuf)
+		if err == nil {
+			err = p.checkConsumed(bodyBuf, ErrRequestBodyLength)
+		}
 		if err == nil {
-			req.SetAttachments(attatchments)
+			req.SetAttachments(withHeadAttachments(req, attatchments))
 		} else {
 			req.SetBroken(true)
 			req.SetData(err.Error())
@@ -404,36 +949,208 @@ func (p *DubboCodec) DecodeDubboReqBody(req *Request, bodyBuf *util.ReadBuffer)
 	return 0
 }
 
-//DecodeDubboReqHead is a method which decodes dubbo request header
+//rawSpan returns a copy of the bytes read from buffer since start if the codec captures them,
+//the buffer may be reused once decoded
+func (p *DubboCodec) rawSpan(buffer *util.ReadBuffer, start int) []byte {
+	if !p.CaptureRaw {
+		return nil
+	}
+	data := buffer.Bytes()
+	end := buffer.Consumed()
+	if end > len(data) {
+		end = len(data)
+	}
+	return append([]byte(nil), data[start:end]...)
+}
+
+//checkConsumed returns mismatch in strict mode if decoding did not read exactly the body in buffer,
+//which means the body is corrupt or misaligned with the decoder
+func (p *DubboCodec) checkConsumed(buffer *util.ReadBuffer, mismatch *CodecError) error {
+	if p.Strict && buffer.Consumed() != len(buffer.Bytes()) {
+		return mismatch
+	}
+	return nil
+}
+
+//coerceArguments boxes or unboxes the arguments to the types expected by the resolved method
+func (p *DubboCodec) coerceArguments(req *Request, args []util.Argument) {
+	if p.ExpectedDescriptor == nil {
+		return
+	}
+	if desc, ok := p.ExpectedDescriptor(req.GetAttachment(PathKey, ""), req.GetMethodName()); ok {
+		util.CoerceArguments(args, desc)
+	}
+}
+
+func (p *DubboCodec) readAttachments(req *Request, s util.BodySerializer, bodyBuf *util.ReadBuffer) (map[string]string, error) {
+	attachments, err := s.ReadMap(bodyBuf)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := attachments[SerializationKey]; ok && req.GetSerialization() != 0 {
+		if id, known := serializationNames[name]; !known || id != req.GetSerialization() {
+			return nil, ErrSerializationMismatch
+		}
+	}
+	if p.Strict {
+		for _, v := range attachments {
+			if !utf8.ValidString(v) {
+				return nil, ErrInvalidCharset
+			}
+		}
+	}
+	return p.filterAttachments(attachments), nil
+}
+
+//checkBodyHead checks the interface of req and the number of arguments declared by its type descriptor
+func (p *DubboCodec) checkBodyHead(req *Request, typeDesc string) error {
+	if err := p.checkInterface(req); err != nil {
+		return err
+	}
+	arity, ok := p.MethodArity[req.GetMethodName()]
+	if !ok {
+		return nil
+	}
+	count := 0
+	if typeDesc != "" {
+		count = len(util.TypeDesToArgsObjArry(typeDesc))
+	}
+	if count != arity {
+		return ErrArityMismatch
+	}
+	return nil
+}
+
+//keepPartial sets the arguments decoded before an argument failed to decode on req, when codec keeps
+//partial requests
+func (p *DubboCodec) keepPartial(req *Request, decoded []util.Argument) {
+	if p.KeepPartialRequests {
+		req.SetArguments(decoded)
+	}
+}
+
+//checkInterface rejects req when its interface is not the expected interface of codec, the status of req
+//is set to the status of the error
+func (p *DubboCodec) checkInterface(req *Request) error {
+	if p.ExpectedInterface == "" || req.GetAttachment(PathKey, "") == p.ExpectedInterface {
+		return nil
+	}
+	req.status = ErrUnexpectedInterface.Status
+	return ErrUnexpectedInterface
+}
+
+//withHeadAttachments returns the attachments read from the body head of req when attachments is the empty
+//map of minimal frames, other attachments are returned unchanged
+func withHeadAttachments(req *Request, attachments map[string]string) map[string]string {
+	if attachments == nil || len(attachments) > 0 {
+		return attachments
+	}
+	for _, key := range []string{DubboVersionKey, PathKey, VersionKey} {
+		attachments[key] = req.GetAttachment(key, "")
+	}
+	return attachments
+}
+
+//protocolAttachments are the attachment keys the dubbo protocol needs, they are never dropped
+var protocolAttachments = map[string]bool{
+	DubboVersionKey: true,
+	PathKey:         true,
+	InterfaceKey:    true,
+	VersionKey:      true,
+	GroupKey:        true,
+}
+
+//filterAttachments drops the attachments which are not allowed by codec
+func (p *DubboCodec) filterAttachments(attachments map[string]string) map[string]string {
+	if len(p.AllowedAttachments) == 0 {
+		return attachments
+	}
+	allowed := make(map[string]bool, len(p.AllowedAttachments))
+	for _, key := range p.AllowedAttachments {
+		allowed[key] = true
+	}
+	for key := range attachments {
+		if !allowed[key] && !protocolAttachments[key] {
+			delete(attachments, key)
+		}
+	}
+	return attachments
+}
+
+//registryCategory reads the category parameter of the url argument of registry operations,
+//which is a string or a decoded com.alibaba.dubbo.common.URL
+func registryCategory(url interface{}) string {
+	switch u := url.(type) {
+	case string:
+		idx := strings.Index(u, "?")
+		if idx < 0 {
+			return ""
+		}
+		query, err := neturl.ParseQuery(u[idx+1:])
+		if err != nil {
+			return ""
+		}
+		return query.Get(CategoryKey)
+	case map[string]interface{}:
+		params := util.ToJSONValue(u["parameters"])
+		if m, ok := params.(map[string]interface{}); ok {
+			category, _ := m[CategoryKey].(string)
+			return category
+		}
+	}
+	return ""
+}
+
+//IsHTTP2Preface is a function which checks whether the header is the start of http/2 connection preface
+func IsHTTP2Preface(header []byte) bool {
+	if len(header) < HeaderLength {
+		return false
+	}
+	return string(header[:HeaderLength]) == HTTP2Preface[:HeaderLength]
+}
+
+//DecodeDubboReqHead is a method which decodes dubbo request header. The serialization must be allowed and have a
+//registered serializer able to read bodies. The id, the two-way flag and the body length are decoded before the
+//serialization is checked, so that a request rejected for its serialization can be answered and its body skipped
 func (p *DubboCodec) DecodeDubboReqHead(req *Request, header []byte, bodyLen *int) int {
+	if IsQosCommand(header) {
+		return QosCommandLine
+	}
 	if len(header) < HeaderLength {
 		return NeedMore
 	}
+	if IsHTTP2Preface(header) {
+		return UnsupportedProtocol
+	}
 	//读取Magic
 	if header[0] != MagicHigh || header[1] != MagicLow {
 		return InvalidFragement
 	}
+	var flag = header[2]
+	if (flag & FlagRequest) == 0 {
+		return InvalidFragement
+	}
 	//读取请求ID
-	var id = util.Bytes2long(header, 4)
+	req.SetMsgID(util.Bytes2long(header, 4))
+	req.SetTwoWay((flag & FlagTwoWay) != 0)
+	//读取长度
+	*bodyLen = int(util.Bytes2int(header, 12))
+	if *bodyLen < 0 {
+		return InvalidBodyLength
+	}
 
-	var flag = header[2]
 	proto := byte(flag & SerializationMask)
-
-	if proto != Hessian2 { //当前只支持hessian2编码
-		return InvalidSerialization
+	if p.CheckSerialization(proto) != nil {
+		return SerializationNotAllowed
 	}
-
-	if (flag & FlagRequest) == 0 {
-		return InvalidFragement
+	if _, ok := p.bodySerializer(proto); !ok {
+		return InvalidSerialization
 	}
-	req.SetMsgID(id)
+	req.SetSerialization(proto)
 	req.SetVersion(DubboVersion)
-	req.SetTwoWay((flag & FlagTwoWay) != 0)
 	if (flag & FlagEvent) != 0 {
 		req.SetEvent(HeartBeatEvent)
 	}
-	//读取长度
-	*bodyLen = int(util.Bytes2int(header, 12))
 
 	return Success
 }
@@ -18,7 +18,13 @@
 package dubbo
 
 import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chassis/gohessian"
 	"github.com/go-mesh/mesher/protocol/dubbo/utils"
@@ -46,3 +52,1639 @@ func TestDubboCodec_DecodeDubboReqBody(t *testing.T) {
 	assert.Nil(t, obj)
 	d.DecodeDubboRspBody(rbf, resp)
 }
+
+func newTestRequest() *Request {
+	req := NewDubboRequest()
+	req.SetMethodName("sayHello")
+	req.SetAttachment(PathKey, "com.demo.HelloService")
+	req.SetArguments([]util.Argument{{JavaType: util.JavaString, Value: "world"}})
+	return req
+}
+
+func encodeRequest(t *testing.T, d *DubboCodec, req *Request) []byte {
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, 0, d.EncodeDubboReq(req, &buffer))
+	return buffer.GetValidData()
+}
+
+func decodeRequest(d *DubboCodec, frame []byte) (*Request, int) {
+	req := new(Request)
+	bodyLen := 0
+	if ret := d.DecodeDubboReqHead(req, frame[:HeaderLength], &bodyLen); ret != Success {
+		return req, ret
+	}
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength : HeaderLength+bodyLen])
+	return req, d.DecodeDubboReqBody(req, &body)
+}
+
+func TestDubboCodec_StrictAttachmentCharset(t *testing.T) {
+	req := newTestRequest()
+	req.SetAttachment("token", "\xff\xfe")
+	frame := encodeRequest(t, &DubboCodec{}, req)
+
+	decoded, ret := decodeRequest(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "\xff\xfe", decoded.GetAttachment("token", ""))
+
+	d := &DubboCodec{Strict: true}
+	decoded, ret = decodeRequest(d, frame)
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ErrInvalidCharset.Error(), decoded.GetData())
+
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	for i := 0; i < 6; i++ {
+		_, err := body.ReadObject()
+		assert.NoError(t, err)
+	}
+	_, err := d.readAttachments(new(Request), util.Hessian2Serializer{}, &body)
+	assert.Equal(t, ErrInvalidCharset, err)
+}
+
+func encodeResponse(t *testing.T, d *DubboCodec, rsp *DubboRsp) []byte {
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, 0, d.EncodeDubboRsp(rsp, &buffer))
+	return buffer.GetValidData()
+}
+
+func decodeResponse(d *DubboCodec, frame []byte) (*DubboRsp, int) {
+	rsp := new(DubboRsp)
+	rsp.Init()
+	bodyLen := 0
+	if ret := d.DecodeDubboRsqHead(rsp, frame[:HeaderLength], &bodyLen); ret != Success {
+		return rsp, ret
+	}
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength : HeaderLength+bodyLen])
+	return rsp, d.DecodeDubboRspBody(&body, rsp)
+}
+
+func TestDubboRsp_GenericValue(t *testing.T) {
+	req := newTestRequest()
+	req.SetMethodName(GenericInvoke)
+	assert.True(t, req.IsGeneric())
+	assert.False(t, newTestRequest().IsGeneric())
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(req.GetMsgID())
+	rsp.SetValue(map[string]interface{}{"name": "tom", "tags": []interface{}{"a", "b"}})
+	d := &DubboCodec{}
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	assert.False(t, decoded.IsGeneric())
+	decoded.SetGeneric(req.IsGeneric())
+	assert.True(t, decoded.IsGeneric())
+
+	b, err := json.Marshal(decoded.GetGenericValue())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"tom","tags":["a","b"]}`, string(b))
+
+	decoded.SetValue(map[interface{}]interface{}{"id": map[interface{}]interface{}{int32(1): "x"}})
+	b, err = json.Marshal(decoded.GetGenericValue())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":{"1":"x"}}`, string(b))
+}
+
+func TestDubboCodec_MaxDepth(t *testing.T) {
+	var v interface{} = "leaf"
+	for i := 0; i < 64; i++ {
+		v = []interface{}{v}
+	}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: util.JavaObject, Value: v}})
+	frame := encodeRequest(t, &DubboCodec{}, req)
+
+	_, ret := decodeRequest(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+
+	decoded, ret := decodeRequest(&DubboCodec{MaxDepth: 16}, frame)
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, util.ErrMaxDepthExceeded.Error(), decoded.GetData())
+}
+
+func TestNewOneWayRequest(t *testing.T) {
+	req := NewOneWayRequest("com.demo.RegistryService", "notify")
+	req.SetArguments([]util.Argument{{JavaType: util.JavaString, Value: "event"}})
+	assert.False(t, req.IsTwoWay())
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, req)
+	assert.Equal(t, byte(0), frame[2]&FlagTwoWay)
+	assert.Equal(t, FlagRequest, frame[2]&FlagRequest)
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	assert.False(t, decoded.IsTwoWay())
+	assert.Equal(t, "notify", decoded.GetMethodName())
+	assert.Equal(t, "com.demo.RegistryService", decoded.GetAttachment(PathKey, ""))
+
+	assert.Equal(t, FlagTwoWay, encodeRequest(t, d, newTestRequest())[2]&FlagTwoWay)
+}
+
+func TestDubboCodec_HTTP2Preface(t *testing.T) {
+	preface := []byte(HTTP2Preface)
+	assert.True(t, IsHTTP2Preface(preface))
+	assert.False(t, IsHTTP2Preface(preface[:8]))
+
+	d := &DubboCodec{}
+	req := new(Request)
+	bodyLen := 0
+	assert.Equal(t, UnsupportedProtocol, d.DecodeDubboReqHead(req, preface, &bodyLen))
+	assert.Equal(t, 0, bodyLen)
+	assert.Equal(t, int64(0), req.GetMsgID())
+
+	frame := encodeRequest(t, d, newTestRequest())
+	assert.False(t, IsHTTP2Preface(frame))
+	assert.Equal(t, Success, d.DecodeDubboReqHead(req, frame[:HeaderLength], &bodyLen))
+}
+
+func TestDubboCodec_NegativeBodyLength(t *testing.T) {
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, newTestRequest())
+	util.Int2bytes(-1, frame, 12)
+	bodyLen := 0
+	assert.Equal(t, InvalidBodyLength, d.DecodeDubboReqHead(new(Request), frame[:HeaderLength], &bodyLen))
+	_, err := decodeRequestFrame(d, frame)
+	assert.Equal(t, ErrNegativeBodyLength, err)
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue("hello")
+	rspFrame := encodeResponse(t, d, rsp)
+	util.Int2bytes(-1, rspFrame, 12)
+	assert.Equal(t, InvalidBodyLength, d.DecodeDubboRsqHead(&DubboRsp{}, rspFrame[:HeaderLength], &bodyLen))
+	var body util.ReadBuffer
+	body.SetBuffer(rspFrame[HeaderLength:])
+	_, err = d.DecodeResponse(rspFrame[:HeaderLength], &body)
+	assert.Equal(t, ErrNegativeBodyLength, err)
+}
+
+//jsonSerializer writes values as json prefixed by their length
+type jsonSerializer struct{}
+
+func (jsonSerializer) ReadObject(b *util.ReadBuffer) (interface{}, error) {
+	n := util.Bytes2int(b.ReadBytes(4), 0)
+	var v interface{}
+	err := json.Unmarshal(b.ReadBytes(int(n)), &v)
+	return v, err
+}
+
+func (jsonSerializer) WriteObject(b *util.WriteBuffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	util.Int2bytes(len(data), size, 0)
+	b.WriteBytes(size)
+	b.WriteBytes(data)
+	return nil
+}
+
+func TestDubboCodec_RegisterSerializerWhileDecoding(t *testing.T) {
+	const fastJSON = byte(6)
+	const jsonType = "Lcom/demo/JsonPayload;"
+	util.RegisterSerializer(fastJSON, jsonSerializer{})
+	util.RegisterArgumentSerialization(jsonType, fastJSON)
+	defer util.RegisterSerializer(fastJSON, nil)
+	defer util.RegisterArgumentSerialization(jsonType, 0)
+
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: jsonType, Value: "payload"}})
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, req)
+
+	//other serializations are registered by another goroutine during the decodes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			util.RegisterSerializer(9, jsonSerializer{})
+			util.RegisterArgumentSerialization("Lcom/demo/Other;", 9)
+			util.RegisterSerializer(9, nil)
+			util.RegisterArgumentSerialization("Lcom/demo/Other;", 0)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		decoded, ret := decodeRequest(d, frame)
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, "payload", decoded.GetArguments()[0].GetValue())
+	}
+	<-done
+}
+
+func TestDubboCodec_ArgumentSerializer(t *testing.T) {
+	const fastJSON = byte(6)
+	const jsonType = "Lcom/demo/JsonPayload;"
+	util.RegisterSerializer(fastJSON, jsonSerializer{})
+	util.RegisterArgumentSerialization(jsonType, fastJSON)
+	defer util.RegisterSerializer(fastJSON, nil)
+	defer util.RegisterArgumentSerialization(jsonType, 0)
+
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: util.JavaString, Value: "first"},
+		{JavaType: jsonType, Value: map[string]interface{}{"name": "tom"}},
+		{JavaType: util.JavaInteger, Value: int32(7)},
+	})
+	d := &DubboCodec{}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Len(t, args, 3)
+	assert.Equal(t, "first", args[0].GetValue())
+	assert.Equal(t, fastJSON, args[1].GetSerialization())
+	assert.Equal(t, map[string]interface{}{"name": "tom"}, args[1].GetValue())
+	assert.Equal(t, int32(7), args[2].GetValue())
+
+	req.SetArguments([]util.Argument{{JavaType: util.JavaString, Value: "x", Serialization: 99}})
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, -1, d.EncodeDubboReq(req, &buffer))
+}
+
+func TestDubboCodec_BoolArrays(t *testing.T) {
+	yes, no := true, false
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{Value: []bool{true, false, true}},
+		{Value: []*bool{&yes, nil, &no}},
+	})
+	d := &DubboCodec{}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaBoolArray, args[0].GetJavaType())
+	assert.Equal(t, []bool{true, false, true}, args[0].GetValue())
+	assert.Equal(t, util.JavaBooleanArray, args[1].GetJavaType())
+	assert.Equal(t, []*bool{&yes, nil, &no}, args[1].GetValue())
+}
+
+func TestDubboCodec_AllowedSerializations(t *testing.T) {
+	d := &DubboCodec{AllowedSerializations: []byte{Hessian2}}
+	frame := encodeRequest(t, d, newTestRequest())
+	_, ret := decodeRequest(d, frame)
+	assert.Equal(t, Success, ret)
+
+	frame[2] = frame[2]&^SerializationMask | FastJSON
+	req := new(Request)
+	bodyLen := 0
+	assert.Equal(t, SerializationNotAllowed, d.DecodeDubboReqHead(req, frame[:HeaderLength], &bodyLen))
+	assert.Equal(t, ErrSerializationNotAllowed, d.CheckSerialization(FastJSON))
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue("hello")
+	rspFrame := encodeResponse(t, d, rsp)
+	rspFrame[2] = rspFrame[2]&^SerializationMask | FastJSON
+	assert.Equal(t, SerializationNotAllowed, d.DecodeDubboRsqHead(&DubboRsp{}, rspFrame[:HeaderLength], &bodyLen))
+
+	d = &DubboCodec{}
+	assert.NoError(t, d.CheckSerialization(FastJSON))
+	assert.Equal(t, InvalidSerialization, d.DecodeDubboReqHead(req, frame[:HeaderLength], &bodyLen))
+	_, err := decodeRequestFrame(d, frame)
+	assert.Equal(t, ErrUnknownSerialization, err)
+}
+
+//javaPointClass is the class descriptor java.io.ObjectOutputStream writes for
+//class com.demo.Point implements Serializable { int x; int y; String label; }
+const javaPointClass = "\x72\x00\x0ecom.demo.Point\x00\x00\x00\x00\x00\x00\x00\x01\x02\x00\x03" +
+	"I\x00\x01x" + "I\x00\x01y" + "L\x00\x05label\x74\x00\x12Ljava/lang/String;" + "\x78\x70"
+
+//javaTimeoutMap is what java.io.ObjectOutputStream writes for a java.util.HashMap holding timeout=3000
+const javaTimeoutMap = "\x73\x72\x00\x11java.util.HashMap\x05\x07\xda\xc1\xc3\x16\x60\xd1\x03\x00\x02" +
+	"F\x00\x0aloadFactor" + "I\x00\x09threshold" + "\x78\x70" + "\x3f\x40\x00\x00\x00\x00\x00\x0c" +
+	"\x77\x08\x00\x00\x00\x10\x00\x00\x00\x01" + "\x74\x00\x07timeout\x74\x00\x043000" + "\x78"
+
+//javaBlock returns the block of data the java serialization of dubbo writes for short strings written with
+//writeUTF and the marker of the object following them
+func javaBlock(strs ...string) []byte {
+	var data []byte
+	for _, s := range strs {
+		data = append(data, 0, 0, 0, byte(len(s)), 0, byte(len(s)))
+		data = append(data, s...)
+	}
+	data = append(data, 1)
+	return append([]byte{0x77, byte(len(data))}, data...)
+}
+
+//javaFrame returns a frame of the native java serialization holding body after the stream header
+func javaFrame(flag, status byte, body []byte) []byte {
+	frame := []byte{MagicHigh, MagicLow, flag | NativeJava, status, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 0}
+	util.Int2bytes(len(body)+4, frame, 12)
+	frame = append(frame, 0xac, 0xed, 0x00, 0x05)
+	return append(frame, body...)
+}
+
+func TestDubboCodec_JavaSerialization(t *testing.T) {
+	body := javaBlock("2.0.2", "com.demo.HelloService", "0.0.0", "sayHello", "Ljava/lang/String;Lcom/demo/Point;")
+	body = append(body, "\x74\x00\x05world"...)
+	//the label of the point refers to the string of the first argument
+	body = append(body, "\x77\x01\x01\x73"+javaPointClass+"\x00\x00\x00\x01\x00\x00\x00\x02\x71\x00\x7e\x00\x00"...)
+	body = append(body, "\x77\x01\x01"+javaTimeoutMap...)
+	d := &DubboCodec{Strict: true}
+	_, err := decodeRequestFrame(d, javaFrame(FlagRequest|FlagTwoWay, 0, body))
+	assert.Equal(t, ErrUnknownSerialization, err)
+
+	d.JavaSerialization = true
+	req, err := decodeRequestFrame(d, javaFrame(FlagRequest|FlagTwoWay, 0, body))
+	assert.NoError(t, err)
+	assert.Equal(t, NativeJava, req.GetSerialization())
+	assert.Equal(t, int64(7), req.GetMsgID())
+	assert.Equal(t, "sayHello", req.GetMethodName())
+	assert.Equal(t, "3000", req.GetAttachment("timeout", ""))
+	args := req.GetArguments()
+	if assert.Len(t, args, 2) {
+		assert.Equal(t, "world", args[0].GetValue())
+		assert.Equal(t, &util.JavaSerialObject{
+			Class:  "com.demo.Point",
+			Fields: map[string]interface{}{"x": int32(1), "y": int32(2), "label": "world"},
+		}, args[1].GetValue())
+	}
+
+	//the value type and the marker of the value share a block
+	body = append([]byte("\x77\x02\x04\x01\x74\x00\x02ok"), "\x77\x01\x01"+javaTimeoutMap...)
+	rsp, ret := decodeResponse(d, javaFrame(0, Ok, body))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, NativeJava, rsp.GetSerialization())
+	assert.Equal(t, "ok", rsp.GetValue())
+	assert.Equal(t, map[string]string{"timeout": "3000"}, rsp.GetAttachments())
+
+	rsp, ret = decodeResponse(d, javaFrame(0, ServiceNotFound, []byte("\x77\x0a\x00\x00\x00\x04\x00\x04gone")))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, ServiceNotFound, rsp.GetStatus())
+	assert.Equal(t, "gone", rsp.GetErrorMsg())
+}
+
+func TestDubboRPCInvocation_ViaChain(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Nil(t, req.GetViaChain())
+	req.AppendVia("mesher-a:30101")
+
+	hop, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, Success, ret)
+	assert.Equal(t, []string{"mesher-a:30101"}, hop.GetViaChain())
+	hop.AppendVia("mesher-b:30101")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, hop))
+	assert.Equal(t, Success, ret)
+	assert.Equal(t, []string{"mesher-a:30101", "mesher-b:30101"}, decoded.GetViaChain())
+}
+
+func TestDubboCodec_UnknownValueType(t *testing.T) {
+	d := &DubboCodec{Strict: true}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue("hello")
+	frame := encodeResponse(t, d, rsp)
+	frame[HeaderLength] = 0x90 + 9 //int 9 in hessian
+
+	decoded, ret := decodeResponse(d, frame)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, BadResponse, decoded.GetStatus())
+	assert.Equal(t, ErrUnknownValueType.Error(), decoded.GetErrorMsg())
+
+	decoded, ret = decodeResponse(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, Ok, decoded.GetStatus())
+	assert.Nil(t, decoded.GetValue())
+}
+
+func TestNewHeartbeatResponseFor(t *testing.T) {
+	d := &DubboCodec{}
+	req := NewDubboRequest()
+	req.SetEvent(HeartBeatEvent)
+	heartbeat, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, Success, ret)
+	assert.True(t, heartbeat.IsHeartbeat())
+
+	rsp := NewHeartbeatResponseFor(heartbeat)
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, req.GetMsgID(), decoded.GetID())
+	assert.True(t, decoded.IsHeartbeat())
+	assert.Equal(t, Ok, decoded.GetStatus())
+}
+
+func TestDubboCodec_SerializationAttachment(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetAttachment(SerializationKey, "hessian2")
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, Hessian2, decoded.GetSerialization())
+	assert.Equal(t, "hessian2", decoded.GetAttachment(SerializationKey, ""))
+
+	req.SetAttachment(SerializationKey, "fastjson")
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ErrSerializationMismatch.Error(), decoded.GetData())
+}
+
+func TestDubboCodec_StrictBodyLength(t *testing.T) {
+	strict := &DubboCodec{Strict: true}
+	frame := encodeRequest(t, strict, newTestRequest())
+	_, ret := decodeRequest(strict, frame)
+	assert.Equal(t, 0, ret)
+
+	//under-consumption, a byte trails the attachments
+	frame = append(frame, 0x90)
+	util.Int2bytes(len(frame)-HeaderLength, frame, 12)
+	decoded, ret := decodeRequest(strict, frame)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, ErrRequestBodyLength.Error(), decoded.GetData())
+	_, ret = decodeRequest(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue(int32(7))
+	frame = encodeResponse(t, strict, rsp)
+	rspDecoded, ret := decodeResponse(strict, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, int32(7), rspDecoded.GetValue())
+
+	//over-consumption, the body ends after the value type which is read again as the value
+	frame = frame[:HeaderLength+1]
+	util.Int2bytes(1, frame, 12)
+	rspDecoded, ret = decodeResponse(strict, frame)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, BadResponse, rspDecoded.GetStatus())
+	assert.Equal(t, ErrResponseBodyLength.Error(), rspDecoded.GetErrorMsg())
+	rspDecoded, ret = decodeResponse(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, int32(ResponseValue), rspDecoded.GetValue())
+}
+
+func TestDubboCodec_EmptyResponseBody(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue("hello")
+	frame := encodeResponse(t, d, rsp)[:HeaderLength]
+	util.Int2bytes(0, frame, 12)
+
+	decoded, ret := decodeResponse(d, frame)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, BadResponse, decoded.GetStatus())
+	assert.Equal(t, ErrMissingValueType.Error(), decoded.GetErrorMsg())
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	d := &DubboCodec{}
+	for _, c := range []struct {
+		status byte
+		msg    string
+	}{
+		{ClientTimeout, "Waiting server-side response timeout"},
+		{ServerTimeout, "Server-side process timeout"},
+		{BadRequest, "Fail to decode request"},
+		{BadResponse, "Fail to decode response"},
+		{ServiceNotFound, "Not found exported service: com.demo.HelloService"},
+		{ServiceError, "java.lang.IllegalStateException: 服务不可用"},
+		{ServerError, ""},
+		{ClentError, "Client side error"},
+		{ServerThreadPoolExhaustedError, "Thread pool is EXHAUSTED!"},
+	} {
+		rsp := NewErrorResponse(42, c.status, c.msg)
+		decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, int64(42), decoded.GetID())
+		assert.Equal(t, c.status, decoded.GetStatus())
+		assert.Equal(t, c.msg, decoded.GetErrorMsg(), "status %d", c.status)
+		assert.Nil(t, decoded.GetValue())
+	}
+}
+
+func TestDubboCodec_ArrayResult(t *testing.T) {
+	d := &DubboCodec{}
+	results := []interface{}{"ok", int32(7), []interface{}{int32(1), int32(2)}}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetValue(results)
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, results, decoded.GetValue())
+
+	//Object[] written by java, the nested array refers to the type of the outer one
+	frame := encodeResponse(t, d, rsp)[:HeaderLength]
+	body := append([]byte{0x90 + ResponseValue, 0x73, 0x07}, "[object"...)
+	body = append(body, 0x02, 'o', 'k', 0x97, 0x72, 0x90, 0x91, 0x92)
+	util.Int2bytes(len(body), frame, 12)
+	decoded, ret = decodeResponse(d, append(frame, body...))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, Ok, decoded.GetStatus())
+	assert.Equal(t, results, decoded.GetValue())
+}
+
+func decodeResponseResult(t *testing.T, d *DubboCodec, rsp *DubboRsp) *ResponseResult {
+	frame := encodeResponse(t, d, rsp)
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	result, err := d.DecodeResponse(frame[:HeaderLength], &body)
+	assert.NoError(t, err)
+	return result
+}
+
+func TestDubboCodec_DecodeResponse(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(7)
+	rsp.SetValue("hello")
+	result := decodeResponseResult(t, d, rsp)
+	assert.Equal(t, int64(7), result.ID)
+	assert.Equal(t, Ok, result.Status)
+	assert.Equal(t, "hello", result.Value)
+	assert.Nil(t, result.Exception)
+
+	rsp.SetValue(nil)
+	result = decodeResponseResult(t, d, rsp)
+	assert.Equal(t, Ok, result.Status)
+	assert.Nil(t, result.Value)
+
+	rsp.SetException("java.lang.IllegalStateException: boom")
+	result = decodeResponseResult(t, d, rsp)
+	assert.Equal(t, ServiceError, result.Status)
+	assert.Equal(t, "java.lang.IllegalStateException: boom", result.Exception)
+	assert.Nil(t, result.Value)
+
+	_, err := d.DecodeResponse([]byte{MagicHigh}, nil)
+	assert.Equal(t, ErrInvalidHeader, err)
+}
+
+func decodeRequestFrame(d *DubboCodec, frame []byte) (*Request, error) {
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	return d.DecodeRequest(frame[:HeaderLength], &body)
+}
+
+func TestDubboCodec_DecodeRequest(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	decoded, err := decodeRequestFrame(d, encodeRequest(t, d, req))
+	assert.NoError(t, err)
+	assert.Equal(t, req.GetMsgID(), decoded.GetMsgID())
+	assert.Equal(t, "sayHello", decoded.GetMethodName())
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+	assert.True(t, decoded.IsTwoWay())
+
+	heartbeat := NewDubboRequest()
+	heartbeat.SetEvent(HeartBeatEvent)
+	decoded, err = decodeRequestFrame(d, encodeRequest(t, d, heartbeat))
+	assert.NoError(t, err)
+	assert.True(t, decoded.IsHeartbeat())
+
+	decoded, err = decodeRequestFrame(d, encodeRequest(t, d, NewReadOnlyEvent()))
+	assert.NoError(t, err)
+	assert.True(t, decoded.IsReadOnlyEvent())
+
+	_, err = d.DecodeRequest([]byte(HTTP2Preface), nil)
+	assert.Equal(t, ErrUnsupportedProtocol, err)
+}
+
+func TestDubboCodec_DecodeRequestInto(t *testing.T) {
+	d := &DubboCodec{}
+	first := newTestRequest()
+	first.SetAttachment("token", "abc")
+	second := NewOneWayRequest("com.demo.Notify", "notify")
+	second.SetArguments([]util.Argument{{Value: int32(5)}})
+
+	req := new(Request)
+	for _, src := range []*Request{first, second} {
+		frame := encodeRequest(t, d, src)
+		var body util.ReadBuffer
+		body.SetBuffer(frame[HeaderLength:])
+		assert.NoError(t, d.DecodeRequestInto(req, frame[:HeaderLength], &body))
+		assert.Equal(t, src.GetMsgID(), req.GetMsgID())
+		assert.Equal(t, src.IsTwoWay(), req.IsTwoWay())
+		assert.Equal(t, src.GetMethodName(), req.GetMethodName())
+		assert.Equal(t, src.GetArguments()[0].GetValue(), req.GetArguments()[0].GetValue())
+	}
+	assert.Equal(t, "", req.GetAttachment("token", ""))
+
+	assert.Equal(t, ErrUnsupportedProtocol, d.DecodeRequestInto(req, []byte(HTTP2Preface), nil))
+}
+
+func BenchmarkDubboCodec_DecodeRequestInto(b *testing.B) {
+	d := &DubboCodec{}
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	d.EncodeDubboReq(newTestRequest(), &buffer)
+	frame := buffer.GetValidData()
+	req := new(Request)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var body util.ReadBuffer
+		body.SetBuffer(frame[HeaderLength:])
+		d.DecodeRequestInto(req, frame[:HeaderLength], &body)
+	}
+}
+
+func TestDubboCodec_LocaleAndCurrency(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: util.Locale("zh-CN")}, {Value: util.Currency("CNY")}})
+	frame := encodeRequest(t, d, req)
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaLocale, args[0].GetJavaType())
+	assert.Equal(t, util.Locale("zh-CN"), args[0].GetValue())
+	assert.Equal(t, util.JavaCurrency, args[1].GetJavaType())
+	assert.Equal(t, util.Currency("CNY"), args[1].GetValue())
+}
+
+func TestDubboCodec_Pattern(t *testing.T) {
+	d := &DubboCodec{}
+	pattern := util.Pattern{Pattern: "^order-[0-9]+$", Flags: 2 | 8}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: pattern}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaPattern, args[0].GetJavaType())
+	assert.Equal(t, pattern, args[0].GetValue())
+
+	reencoded, ret := decodeRequest(d, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, pattern, reencoded.GetArguments()[0].GetValue())
+}
+
+func TestDubboCodec_InetAddressAndURL(t *testing.T) {
+	d := &DubboCodec{MaxDepth: 8}
+	ip := net.ParseIP("10.0.0.1")
+	u, err := url.Parse("http://registry.demo:80/services?app=mesher#top")
+	assert.NoError(t, err)
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: ip}, {Value: u}, {Value: net.ParseIP("fe80::1")}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaInetAddress, args[0].GetJavaType())
+	assert.Equal(t, ip.To4(), args[0].GetValue())
+	assert.Equal(t, util.JavaURL, args[1].GetJavaType())
+	assert.Equal(t, u.String(), args[1].GetValue().(*url.URL).String())
+	assert.Equal(t, net.ParseIP("fe80::1"), args[2].GetValue())
+}
+
+func TestDubboCodec_AtomicInteger(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: util.JavaAtomicInteger, Value: int32(42)}, {Value: "after"}})
+	frame := encodeRequest(t, d, req)
+	assert.Contains(t, string(frame), "java.util.concurrent.atomic.AtomicInteger")
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaAtomicInteger, args[0].GetJavaType())
+	assert.Equal(t, int32(42), args[0].GetValue())
+	assert.Equal(t, "after", args[1].GetValue())
+}
+
+func TestDubboCodec_AtomicLong(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: util.JavaAtomicLong, Value: int64(1024)}, {Value: "after"}})
+	frame := encodeRequest(t, d, req)
+	assert.Contains(t, string(frame), "java.util.concurrent.atomic.AtomicLong")
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaAtomicLong, args[0].GetJavaType())
+	assert.Equal(t, int64(1024), args[0].GetValue())
+	assert.Equal(t, "after", args[1].GetValue())
+}
+
+func TestDubboCodec_BitSet(t *testing.T) {
+	d := &DubboCodec{}
+	bits := util.NewBitSet(1, 5, 64, 130, 511, 600)
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: bits}, {Value: util.NewBitSet()}, {Value: "after"}})
+	frame := encodeRequest(t, d, req)
+	assert.Contains(t, string(frame), "java.util.BitSet")
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaBitSet, args[0].GetJavaType())
+	assert.Equal(t, bits, args[0].GetValue())
+	for _, bit := range []int{1, 5, 64, 130, 511, 600} {
+		assert.True(t, bits.Test(bit))
+	}
+	assert.False(t, bits.Test(2))
+	assert.Equal(t, util.BitSet{}, args[1].GetValue())
+	assert.Equal(t, "after", args[2].GetValue())
+
+	//java writes the words in their compact forms, here 2 and -1 in one byte and 1<<17 in three
+	var rbf util.ReadBuffer
+	rbf.SetBuffer(append([]byte("C\x10java.util.BitSet\x91\x05words\x60\x73\x05[long"), 0xe2, 0xdf, 0x3e, 0, 0))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, util.BitSet{2, 1<<64 - 1, 1 << 17}, obj)
+}
+
+func TestDubboCodec_EnumSet(t *testing.T) {
+	d := &DubboCodec{MaxDepth: 8}
+	colors := []util.JavaEnum{{Class: "com.demo.Color", Name: "RED"}, {Class: "com.demo.Color", Name: "GREEN"}}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: colors}, {Value: []util.JavaEnum{}}, {Value: "after"}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaEnumSet, args[0].GetJavaType())
+	assert.Equal(t, colors, args[0].GetValue())
+	assert.Equal(t, []util.JavaEnum{}, args[1].GetValue())
+	assert.Equal(t, "after", args[2].GetValue())
+}
+
+func TestDubboCodec_EnumMap(t *testing.T) {
+	d := &DubboCodec{MaxDepth: 8}
+	levels := map[util.JavaEnum]interface{}{
+		{Class: "com.demo.Level", Name: "HIGH"}: "urgent",
+		{Class: "com.demo.Level", Name: "LOW"}:  int32(3),
+		{Class: "com.demo.Level", Name: "MID"}:  nil,
+	}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: levels}, {Value: "after"}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, util.JavaEnumMap, args[0].GetJavaType())
+	assert.Equal(t, map[string]interface{}{"HIGH": "urgent", "LOW": int32(3), "MID": nil}, args[0].GetValue())
+	assert.Equal(t, "after", args[1].GetValue())
+}
+
+func TestDubboCodec_MaxStringLength(t *testing.T) {
+	d := &DubboCodec{MaxStringLength: 1024}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "sayHello", decoded.GetMethodName())
+
+	//the path claims the longest string chunk, none of its characters follow
+	frame := encodeRequest(t, d, newTestRequest())[:HeaderLength]
+	body := append([]byte{0x05}, DubboVersion...)
+	body = append(body, 'S', 0xff, 0xff)
+	util.Int2bytes(len(body), frame, 12)
+	decoded, ret = decodeRequest(d, append(frame, body...))
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, util.ErrStringTooLong.Error(), decoded.GetData())
+}
+
+func TestDubboCodec_MaxArgumentSize(t *testing.T) {
+	d := &DubboCodec{MaxArgumentSize: 1 << 20}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: strings.Repeat("x", 10<<20+10)}})
+	frame := encodeRequest(t, d, req)
+
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ErrArgumentTooLarge.Error(), decoded.GetData())
+
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+}
+
+type registryURL struct {
+	Protocol   string
+	Host       string
+	Parameters map[string]string
+}
+
+func decodeRegistryRequest(t *testing.T, d *DubboCodec, url interface{}) *Request {
+	req := NewDubboRequest()
+	req.SetMethodName("subscribe")
+	req.SetAttachment(PathKey, "com.alibaba.dubbo.registry.RegistryService")
+	req.SetArguments([]util.Argument{{JavaType: "Lcom/alibaba/dubbo/common/URL;", Value: url}})
+	frame := encodeRequest(t, d, req)
+
+	decoded := new(Request)
+	bodyLen := 0
+	assert.Equal(t, Success, d.DecodeDubboReqHead(decoded, frame[:HeaderLength], &bodyLen))
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	assert.Equal(t, 0, d.DecodeDubboReqBodyForRegstry(decoded, &body))
+	return decoded
+}
+
+func TestDubboCodec_RegistryCategory(t *testing.T) {
+	d := &DubboCodec{}
+	decoded := decodeRegistryRequest(t, d, "consumer://10.0.0.1/com.demo.HelloService?category=providers,routers&side=consumer")
+	assert.Equal(t, "subscribe", decoded.GetMethodName())
+	assert.Equal(t, "providers,routers", decoded.GetRegistryCategory())
+
+	url := registryURL{"consumer", "10.0.0.1", map[string]string{CategoryKey: "configurators"}}
+	decoded = decodeRegistryRequest(t, d, url)
+	assert.Equal(t, "configurators", decoded.GetRegistryCategory())
+
+	decoded = decodeRegistryRequest(t, d, "consumer://10.0.0.1/com.demo.HelloService")
+	assert.Equal(t, "", decoded.GetRegistryCategory())
+}
+
+func TestDubboCodec_ExpectedDescriptor(t *testing.T) {
+	d := &DubboCodec{ExpectedDescriptor: func(path string, method string) (string, bool) {
+		return "ILjava/lang/Long;", path == "com.demo.HelloService" && method == "sayHello"
+	}}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: util.JavaInteger, Value: int32(5)}, {JavaType: "J", Value: int64(100)}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, "I", args[0].GetJavaType())
+	assert.Equal(t, int32(5), args[0].GetValue())
+	assert.Equal(t, util.JavaLong, args[1].GetJavaType())
+	assert.Equal(t, int64(100), args[1].GetValue())
+
+	decoded, _ = decodeRequest(&DubboCodec{}, encodeRequest(t, d, req))
+	assert.Equal(t, util.JavaInteger, decoded.GetArguments()[0].GetJavaType())
+}
+
+func TestDubboRPCInvocation_EnsureTimeout(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Equal(t, 3*time.Second, req.EnsureTimeout(3*time.Second))
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "3000", decoded.GetAttachment(TimeoutKey, ""))
+
+	req = newTestRequest()
+	req.SetAttachment(TimeoutKey, "500")
+	assert.Equal(t, 500*time.Millisecond, req.EnsureTimeout(3*time.Second))
+	decoded, _ = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, "500", decoded.GetAttachment(TimeoutKey, ""))
+}
+
+func TestDubboRPCInvocation_ProtocolUpgradeHint(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Equal(t, "", req.GetProtocolUpgradeHint())
+	req.SetProtocolUpgradeHint("tri")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "tri", decoded.GetProtocolUpgradeHint())
+
+	reencoded, ret := decodeRequest(d, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "tri", reencoded.GetProtocolUpgradeHint())
+}
+
+func TestDubboRPCInvocation_ConsumerMetadata(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Equal(t, "", req.GetSide())
+	assert.Nil(t, req.GetMethods())
+	req.SetAttachment(SideKey, ConsumerSide)
+	req.SetAttachment(MethodsKey, "sayHello,sayBye")
+	req.SetAttachment("pid", "4242")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, ConsumerSide, decoded.GetSide())
+	assert.Equal(t, []string{"sayHello", "sayBye"}, decoded.GetMethods())
+	assert.Equal(t, "4242", decoded.GetAttachment("pid", ""))
+}
+
+func TestRequest_GetMethods(t *testing.T) {
+	cases := []struct {
+		methods string
+		want    []string
+	}{
+		{"", nil},
+		{",", nil},
+		{"sayHello", []string{"sayHello"}},
+		{"sayHello,sayBye", []string{"sayHello", "sayBye"}},
+		{" sayHello, ,sayBye,", []string{"sayHello", "sayBye"}},
+	}
+	for _, c := range cases {
+		req := newTestRequest()
+		req.SetAttachment(MethodsKey, c.methods)
+		assert.Equal(t, c.want, req.GetMethods(), c.methods)
+	}
+}
+
+func TestRequest_GetExecutor(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Equal(t, "", req.GetExecutor())
+	req.SetAttachment(ThreadPoolKey, "shared")
+	assert.Equal(t, "shared", req.GetExecutor())
+	req.SetAttachment(ExecutorKey, "orders-pool")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "orders-pool", decoded.GetExecutor())
+	assert.Equal(t, "shared", decoded.GetAttachment(ThreadPoolKey, ""))
+}
+
+func TestDubboCodec_PreserveAttachments(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetAttachment("x-custom-trace", "t-1")
+	req.SetAttachment("tenant.id", "42")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, req.GetAttachments(), decoded.GetAttachments())
+}
+
+func TestDubboCodec_AllowedAttachments(t *testing.T) {
+	d := &DubboCodec{AllowedAttachments: []string{"tenant.id", TimeoutKey}}
+	req := newTestRequest()
+	req.SetAttachment(VersionKey, "1.0.0")
+	req.SetAttachment(TimeoutKey, "3000")
+	req.SetAttachment("tenant.id", "42")
+	req.SetAttachment("x-custom-trace", "t-1")
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, map[string]string{
+		PathKey:     "com.demo.HelloService",
+		VersionKey:  "1.0.0",
+		TimeoutKey:  "3000",
+		"tenant.id": "42",
+	}, decoded.GetAttachments())
+}
+
+func TestDubboRPCInvocation_ConsumerIdentity(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	assert.Equal(t, 0, req.GetConsumerPID())
+	assert.Equal(t, "com.demo.HelloService", req.GetConsumerInterface())
+	req.SetAttachment(PidKey, "4242")
+	req.SetAttachment(InterfaceKey, "com.demo.GreetingService")
+	req.SetAttachment(SideKey, ConsumerSide)
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, 4242, decoded.GetConsumerPID())
+	assert.Equal(t, "com.demo.GreetingService", decoded.GetConsumerInterface())
+	assert.Equal(t, "sayHello", decoded.GetMethodName())
+
+	decoded.SetAttachment(PidKey, "not-a-pid")
+	assert.Equal(t, 0, decoded.GetConsumerPID())
+}
+
+func TestRequest_AsShadow(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetAttachment("traceId", "abc")
+	shadow := req.AsShadow()
+	shadow.SetAttachment("shadow", "true")
+	shadow.SetArguments(nil)
+
+	assert.True(t, req.IsTwoWay())
+	assert.False(t, shadow.IsTwoWay())
+	assert.NotEqual(t, req.GetMsgID(), shadow.GetMsgID())
+	assert.Equal(t, "", req.GetAttachment("shadow", ""))
+	assert.Len(t, req.GetArguments(), 1)
+
+	frame := encodeRequest(t, d, req.AsShadow())
+	assert.Zero(t, frame[2]&FlagTwoWay)
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	assert.False(t, decoded.IsTwoWay())
+	assert.Equal(t, "abc", decoded.GetAttachment("traceId", ""))
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+}
+
+func TestDubboCodec_ResponseAttachments(t *testing.T) {
+	d := &DubboCodec{}
+	for _, c := range []struct {
+		version  string
+		expected map[string]string
+	}{
+		{"2.0.0", nil},
+		{"2.6.2", nil},
+		{"2.0.2", map[string]string{"traceId": "abc"}},
+		{"2.7.3", map[string]string{"traceId": "abc"}},
+	} {
+		rsp := &DubboRsp{}
+		rsp.Init()
+		rsp.SetVersion(c.version)
+		rsp.SetValue("hello")
+		rsp.SetAttachments(map[string]string{"traceId": "abc"})
+
+		frame := encodeResponse(t, d, rsp)
+		//the value type is written as a compact hessian int
+		if c.expected == nil {
+			assert.Equal(t, 0x90+ResponseValue, frame[HeaderLength], c.version)
+		} else {
+			assert.Equal(t, 0x90+ResponseValueWithAttachments, frame[HeaderLength], c.version)
+		}
+		decoded, ret := decodeResponse(d, frame)
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, "hello", decoded.GetValue())
+		assert.Equal(t, c.expected, decoded.GetAttachments(), c.version)
+	}
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetVersion("2.0.2")
+	rsp.SetValue("hello")
+	rsp.SetAttachments(map[string]string{"traceId": "abc"})
+	frame := encodeResponse(t, d, rsp)
+	//the attachments start after the value type and the compact string hello
+	corrupt := append([]byte{}, frame...)
+	assert.Equal(t, byte('H'), corrupt[HeaderLength+7])
+	corrupt[HeaderLength+7] = 'T'
+	decoded, ret := decodeResponse(d, corrupt)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, BadResponse, decoded.GetStatus())
+	assert.NotEmpty(t, decoded.GetErrorMsg())
+}
+
+//slowSerializer delays every read of jsonSerializer
+type slowSerializer struct {
+	jsonSerializer
+}
+
+func (s slowSerializer) ReadObject(b *util.ReadBuffer) (interface{}, error) {
+	time.Sleep(20 * time.Millisecond)
+	return s.jsonSerializer.ReadObject(b)
+}
+
+func TestDubboCodec_SlowDecode(t *testing.T) {
+	const slowJSON = byte(7)
+	const slowType = "Lcom/demo/SlowPayload;"
+	util.RegisterSerializer(slowJSON, slowSerializer{})
+	util.RegisterArgumentSerialization(slowType, slowJSON)
+	defer util.RegisterSerializer(slowJSON, nil)
+	defer util.RegisterArgumentSerialization(slowType, 0)
+
+	var reported []SlowDecode
+	d := &DubboCodec{
+		SlowDecodeThreshold: 10 * time.Millisecond,
+		OnSlowDecode:        func(s SlowDecode) { reported = append(reported, s) },
+	}
+	frame := encodeRequest(t, d, newTestRequest())
+	_, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	assert.Empty(t, reported)
+
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: slowType, Value: "payload"}})
+	frame = encodeRequest(t, d, req)
+	_, ret = decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	assert.Len(t, reported, 1)
+	assert.Equal(t, req.GetAttachment(PathKey, ""), reported[0].Interface)
+	assert.Equal(t, req.GetMethodName(), reported[0].Method)
+	assert.Equal(t, len(frame)-HeaderLength, reported[0].Size)
+	assert.True(t, reported[0].Elapsed >= 10*time.Millisecond)
+}
+
+func TestRequest_ServiceKey(t *testing.T) {
+	for _, c := range []struct {
+		version, group, expected string
+	}{
+		{"", "", "com.demo.Hello"},
+		{"0.0.0", "", "com.demo.Hello"},
+		{"1.0.0", "", "com.demo.Hello:1.0.0"},
+		{"", "gray", "com.demo.Hello::gray"},
+		{"1.0.0", "gray", "com.demo.Hello:1.0.0:gray"},
+	} {
+		req := NewDubboRequest()
+		req.SetAttachment(PathKey, "com.demo.Hello")
+		if c.version != "" {
+			req.SetAttachment(VersionKey, c.version)
+		}
+		if c.group != "" {
+			req.SetAttachment(GroupKey, c.group)
+		}
+		assert.Equal(t, c.expected, req.ServiceKey())
+	}
+
+	req := NewDubboRequest()
+	req.SetAttachment(PathKey, "com.demo.HelloProxy")
+	req.SetAttachment(InterfaceKey, "com.demo.Hello")
+	assert.Equal(t, "com.demo.Hello", req.ServiceKey())
+}
+
+func TestRequest_WantsKeepAlive(t *testing.T) {
+	d := &DubboCodec{}
+	for value, expected := range map[string]bool{"": true, "true": true, "false": false, "Close": false} {
+		req := newTestRequest()
+		if value != "" {
+			req.SetAttachment(KeepAliveKey, value)
+		}
+		decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, expected, decoded.WantsKeepAlive(), "keepalive %q", value)
+	}
+}
+
+func TestDubboCodec_OnMethodDecoded(t *testing.T) {
+	var calls []string
+	d := &DubboCodec{OnMethodDecoded: func(interfaceName, method string) {
+		calls = append(calls, interfaceName+"#"+method)
+	}}
+	req := newTestRequest()
+	_, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	req.SetMethodName("sayBye")
+	_, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	heartbeat := NewDubboRequest()
+	heartbeat.SetEvent(HeartBeatEvent)
+	_, ret = decodeRequest(d, encodeRequest(t, d, heartbeat))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, []string{"com.demo.HelloService#sayHello", "com.demo.HelloService#sayBye"}, calls)
+}
+
+func TestRequest_IsSticky(t *testing.T) {
+	d := &DubboCodec{AllowedAttachments: []string{"tenant.id", StickyKey}}
+	for value, expected := range map[string]bool{"": false, "true": true, "TRUE": true, "false": false} {
+		req := newTestRequest()
+		if value != "" {
+			req.SetAttachment(StickyKey, value)
+		}
+		decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, expected, decoded.IsSticky(), "sticky %q", value)
+	}
+
+	//the hint is dropped unless it is allowed
+	req := newTestRequest()
+	req.SetAttachment(StickyKey, "true")
+	dropped, ret := decodeRequest(&DubboCodec{AllowedAttachments: []string{"tenant.id"}}, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.False(t, dropped.IsSticky())
+}
+
+func TestRequest_ValidationGroups(t *testing.T) {
+	d := &DubboCodec{AllowedAttachments: []string{"tenant.id", ValidationKey, ValidationGroupKey}}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "", decoded.GetValidation())
+	assert.Nil(t, decoded.GetValidationGroups())
+
+	req := newTestRequest()
+	req.SetAttachment(ValidationKey, "jvalidation")
+	req.SetAttachment(ValidationGroupKey, "com.demo.Create,com.demo.Update")
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "jvalidation", decoded.GetValidation())
+	assert.Equal(t, []string{"com.demo.Create", "com.demo.Update"}, decoded.GetValidationGroups())
+
+	//forwarded requests keep the attachments
+	forwarded, ret := decodeRequest(&DubboCodec{}, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, decoded.GetValidationGroups(), forwarded.GetValidationGroups())
+
+	//the attachments are dropped unless they are allowed
+	dropped, ret := decodeRequest(&DubboCodec{AllowedAttachments: []string{"tenant.id"}}, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "", dropped.GetValidation())
+	assert.Nil(t, dropped.GetValidationGroups())
+}
+
+func TestRequest_GetLoadBalance(t *testing.T) {
+	d := &DubboCodec{AllowedAttachments: []string{"tenant.id", LoadBalanceKey}}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "", decoded.GetLoadBalance())
+
+	req := newTestRequest()
+	req.SetAttachment(LoadBalanceKey, "roundrobin")
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "roundrobin", decoded.GetLoadBalance())
+	forwarded, ret := decodeRequest(&DubboCodec{}, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "roundrobin", forwarded.GetLoadBalance())
+
+	//the hint is dropped unless it is allowed
+	dropped, ret := decodeRequest(&DubboCodec{AllowedAttachments: []string{"tenant.id"}}, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "", dropped.GetLoadBalance())
+}
+
+func newLargeRequest() *Request {
+	items := make([]interface{}, 2000)
+	for i := range items {
+		items[i] = "item-value-of-twenty"
+	}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: items}})
+	return req
+}
+
+func TestDubboCodec_EstimateSize(t *testing.T) {
+	d := &DubboCodec{}
+	for _, req := range []*Request{newTestRequest(), newLargeRequest()} {
+		size := len(encodeRequest(t, d, req))
+		estimate := d.EstimateSize(req)
+		assert.True(t, estimate >= size, "estimate %d for %d bytes", estimate, size)
+		assert.True(t, estimate < 2*size, "estimate %d for %d bytes", estimate, size)
+	}
+}
+
+func benchmarkEncodeDubboReq(b *testing.B, hint bool) {
+	d := &DubboCodec{}
+	req := newLargeRequest()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buffer util.WriteBuffer
+		if hint {
+			buffer.Init(d.EstimateSize(req))
+		} else {
+			buffer.Init(0)
+		}
+		d.EncodeDubboReq(req, &buffer)
+	}
+}
+
+func BenchmarkDubboCodec_EncodeDubboReq(b *testing.B) {
+	b.Run("default", func(b *testing.B) {
+		benchmarkEncodeDubboReq(b, false)
+	})
+	b.Run("hint", func(b *testing.B) {
+		benchmarkEncodeDubboReq(b, true)
+	})
+}
+
+//person is mapped to the java record com.demo.Person(String name, int age)
+type person struct {
+	Name string
+	Age  int32
+}
+
+func TestDubboCodec_Record(t *testing.T) {
+	util.RegisterJavaType("com.demo.Person", person{})
+	defer util.UnregisterJavaType("com.demo.Person")
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: "Lcom/demo/Person;", Value: person{"alice", 30}}})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, &person{"alice", 30}, decoded.GetArguments()[0].GetValue())
+
+	reencoded, ret := decodeRequest(d, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, &person{"alice", 30}, reencoded.GetArguments()[0].GetValue())
+}
+
+func TestDubboCodec_RegisterJavaTypeWhileDecoding(t *testing.T) {
+	util.RegisterJavaType("com.demo.Person", person{})
+	defer util.UnregisterJavaType("com.demo.Person")
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: "Lcom/demo/Person;", Value: person{"alice", 30}}})
+	frame := encodeRequest(t, d, req)
+
+	//other classes are registered by another goroutine during the decodes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			util.RegisterJavaTypeFields("com.demo.Shape", shape{}, map[string]string{"shape_name": "Name"})
+			util.UnregisterJavaType("com.demo.Shape")
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		decoded, ret := decodeRequest(d, frame)
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, &person{"alice", 30}, decoded.GetArguments()[0].GetValue())
+	}
+	<-done
+}
+
+//shape, circle and square are mapped to the abstract java class com.demo.Shape and two of its subclasses
+type shape struct {
+	Name string
+}
+
+type circle struct {
+	Radius int32
+}
+
+type square struct {
+	Side int32
+}
+
+func TestDubboCodec_Subclass(t *testing.T) {
+	util.RegisterJavaType("com.demo.Shape", shape{})
+	util.RegisterJavaType("com.demo.Circle", circle{})
+	util.RegisterJavaType("com.demo.Square", square{})
+	defer util.UnregisterJavaType("com.demo.Shape")
+	defer util.UnregisterJavaType("com.demo.Circle")
+	defer util.UnregisterJavaType("com.demo.Square")
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: "Lcom/demo/Shape;", Value: circle{3}},
+		{JavaType: "Lcom/demo/Shape;", Value: square{4}},
+	})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, "Lcom/demo/Shape;", args[0].GetJavaType())
+	assert.Equal(t, &circle{3}, args[0].GetValue())
+	assert.Equal(t, &square{4}, args[1].GetValue())
+}
+
+func TestDubboCodec_DubboVersionRewriter(t *testing.T) {
+	req := newTestRequest()
+	req.SetAttachment(DubboVersionKey, "2.0.0")
+	consumer, ret := decodeRequest(&DubboCodec{}, encodeRequest(t, &DubboCodec{}, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "2.0.0", consumer.GetAttachment(DubboVersionKey, ""))
+
+	d := &DubboCodec{DubboVersionRewriter: func(in string) string {
+		if in == "2.0.0" {
+			return "2.0.2"
+		}
+		return in
+	}}
+	provider, ret := decodeRequest(&DubboCodec{}, encodeRequest(t, d, consumer))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "2.0.2", provider.GetAttachment(DubboVersionKey, ""))
+	assert.Equal(t, "2.0.0", consumer.GetAttachment(DubboVersionKey, ""))
+	assert.Equal(t, consumer.GetArguments(), provider.GetArguments())
+}
+
+func TestDubboCodec_CaptureRaw(t *testing.T) {
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: util.JavaString, Value: "world"},
+		{JavaType: util.JavaInteger, Value: int32(7)},
+	})
+	frame := encodeRequest(t, &DubboCodec{}, req)
+	legacy, ret := decodeRequest(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+	assert.Nil(t, legacy.GetRawArguments())
+
+	d := &DubboCodec{CaptureRaw: true}
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+	var expected util.WriteBuffer
+	expected.Init(0)
+	expected.WriteObject("world")
+	expected.WriteObject(int32(7))
+	assert.Equal(t, expected.GetValidData(), decoded.GetRawArguments())
+	var raw util.ReadBuffer
+	raw.SetBuffer(decoded.GetRawArguments())
+	for _, arg := range decoded.GetArguments() {
+		obj, err := raw.ReadObject()
+		assert.NoError(t, err)
+		assert.Equal(t, arg.GetValue(), obj)
+	}
+
+	for _, version := range []string{"2.0.0", "2.0.2"} {
+		rsp := &DubboRsp{}
+		rsp.Init()
+		rsp.SetVersion(version)
+		rsp.SetValue("hello")
+		rsp.SetAttachments(map[string]string{"trace": "1"})
+		decodedRsp, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+		assert.Equal(t, 0, ret)
+		assert.Equal(t, "hello", decodedRsp.GetValue())
+		raw.SetBuffer(decodedRsp.GetRawValue())
+		assert.Equal(t, "hello", raw.ReadString())
+		assert.Equal(t, len(decodedRsp.GetRawValue()), raw.Consumed())
+	}
+}
+
+//itemStream is a snapshot of a stream result holding its elements in its only field
+type itemStream struct {
+	Elements []interface{}
+}
+
+func TestDubboCodec_StreamArgument(t *testing.T) {
+	d := &DubboCodec{}
+	expected := []interface{}{"a", "b", int32(3)}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: util.JavaStream, Value: expected},
+		{JavaType: util.JavaStream, Value: itemStream{expected}},
+	})
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, expected, args[0].GetValue())
+	assert.Equal(t, expected, args[1].GetValue())
+
+	//an object of another class is not taken for a snapshot even if it has one field
+	req.SetArguments([]util.Argument{{JavaType: util.JavaStream, Value: shape{"circle"}}})
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+}
+
+//item is mapped to the java class com.app.Item
+type item struct {
+	Name  string
+	Count int32
+}
+
+func TestDubboCodec_ObjectArray(t *testing.T) {
+	util.RegisterJavaType("com.app.Item", item{})
+	defer util.UnregisterJavaType("com.app.Item")
+	d := &DubboCodec{}
+	items := []*item{{"apple", 3}, nil, {"pear", 5}}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: "[Lcom/app/Item;", Value: items},
+		{JavaType: "[Lcom/app/Unknown;", Value: []interface{}{map[string]interface{}{"id": "a"}, nil}},
+		{JavaType: "[Ljava/lang/String;", Value: []interface{}{"x", "y"}},
+	})
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	args := decoded.GetArguments()
+	assert.Equal(t, items, args[0].GetValue())
+	assert.Equal(t, []map[string]interface{}{{"id": "a"}, nil}, args[1].GetValue())
+	assert.Equal(t, []interface{}{"x", "y"}, args[2].GetValue())
+
+	reencoded, ret := decodeRequest(d, encodeRequest(t, d, decoded))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, items, reencoded.GetArguments()[0].GetValue())
+}
+
+func TestDubboCodec_IntArguments(t *testing.T) {
+	util.RegisterJavaType("com.app.Item", item{})
+	defer util.UnregisterJavaType("com.app.Item")
+	d := &DubboCodec{}
+	ints := []int32{19800, -19800, -1, -16, -17, -2048, -262144}
+	req := newTestRequest()
+	var args []util.Argument
+	for _, i := range ints {
+		args = append(args, util.Argument{JavaType: util.JavaInteger, Value: i})
+	}
+	args = append(args,
+		util.Argument{JavaType: util.JavaList, Value: []interface{}{int32(-19800), "pear", int32(-17)}},
+		util.Argument{JavaType: "com.app.Item", Value: &item{"apple", 19800}})
+	req.SetArguments(args)
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	values := decoded.GetArguments()
+	for i, v := range ints {
+		assert.Equal(t, v, values[i].GetValue())
+	}
+	assert.Equal(t, []interface{}{int32(-19800), "pear", int32(-17)}, values[len(ints)].GetValue())
+	assert.Equal(t, &item{"apple", 19800}, values[len(ints)+1].GetValue())
+}
+
+func TestDubboCodec_LongArguments(t *testing.T) {
+	d := &DubboCodec{}
+	longs := []int64{4102444800, -1 << 40, 1 << 40, 1 << 20, -1, -8, 15, -2048, -262144}
+	req := newTestRequest()
+	var args []util.Argument
+	for _, l := range longs {
+		args = append(args, util.Argument{JavaType: util.JavaLong, Value: l})
+	}
+	req.SetArguments(args)
+
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	for i, v := range longs {
+		assert.Equal(t, v, decoded.GetArguments()[i].GetValue())
+	}
+
+	//java writes longs nested in objects in their compact forms
+	body := []byte{'H', 0x01, 'a', 0x3b, 0xb2, 0xa8, 0x01, 'b', 0xf7, 0x00, 0x01, 'c', 0xdf, 'Z'}
+	var buf util.ReadBuffer
+	buf.SetBuffer(body)
+	obj, err := buf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": int64(-19800), "b": int64(-256), "c": int64(-1)}, obj)
+}
+
+func TestDubboCodec_QosCommand(t *testing.T) {
+	d := &DubboCodec{}
+	frame := []byte("ls -l com.demo.HelloService\r\n")
+	bodyLen := 0
+	assert.Equal(t, QosCommandLine, d.DecodeDubboReqHead(new(Request), frame, &bodyLen))
+	_, err := decodeRequestFrame(d, append(frame, make([]byte, HeaderLength)...))
+	assert.Equal(t, ErrQosCommand, err)
+
+	cmd, ok := ParseQosCommand(frame)
+	assert.True(t, ok)
+	assert.Equal(t, &QosCommand{"ls", []string{"-l", "com.demo.HelloService"}}, cmd)
+	assert.Equal(t, "Unsupported command: ls", HandleQosCommand(cmd))
+	RegisterQosHandler("ls", func(cmd *QosCommand) string { return "PROVIDER:\r\n" + cmd.Args[1] })
+	defer RegisterQosHandler("ls", nil)
+	assert.Equal(t, "PROVIDER:\r\ncom.demo.HelloService", HandleQosCommand(cmd))
+
+	_, ok = ParseQosCommand([]byte("online"))
+	assert.False(t, ok)
+	_, ok = ParseQosCommand([]byte("rm -rf\n"))
+	assert.False(t, ok)
+	_, ok = ParseQosCommand(encodeRequest(t, d, newTestRequest()))
+	assert.False(t, ok)
+}
+
+func TestDubboCodec_OmitAttachments(t *testing.T) {
+	req := newTestRequest()
+	req.SetAttachment(VersionKey, "1.0.0")
+	req.SetAttachment("traceId", "0af7651916cd43dd8448eb211c80319c")
+	full := encodeRequest(t, &DubboCodec{}, req)
+
+	req.SetOmitAttachments(true)
+	minimal := encodeRequest(t, &DubboCodec{}, req)
+	assert.True(t, len(minimal) < len(full))
+	other := *req
+	other.SetOmitAttachments(false)
+	assert.Equal(t, minimal, encodeRequest(t, &DubboCodec{OmitAttachments: true}, &other))
+	assert.True(t, (&DubboCodec{}).EstimateSize(req) >= len(minimal))
+
+	decoded, ret := decodeRequest(&DubboCodec{}, minimal)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, map[string]string{
+		DubboVersionKey: DubboVersion,
+		PathKey:         "com.demo.HelloService",
+		VersionKey:      "1.0.0",
+	}, decoded.GetAttachments())
+	assert.Equal(t, req.GetArguments(), decoded.GetArguments())
+}
+
+func TestDubboCodec_ExpectedInterface(t *testing.T) {
+	d := &DubboCodec{ExpectedInterface: "com.demo.HelloService"}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+
+	req := newTestRequest()
+	req.SetAttachment(PathKey, "com.demo.OrderService")
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ServiceNotFound, decoded.GetStatus())
+	assert.Equal(t, ErrUnexpectedInterface.Error(), decoded.GetData())
+	assert.Nil(t, decoded.GetArguments())
+
+	frame := encodeRequest(t, d, req)
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	_, err := d.DecodeRequest(frame[:HeaderLength], &body)
+	assert.Equal(t, ErrUnexpectedInterface, err)
+}
+
+func TestDubboCodec_KeepPartialRequests(t *testing.T) {
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: "first"}, {Value: "second"}})
+	frame := encodeRequest(t, &DubboCodec{}, req)
+	//the second argument starts with a reserved tag
+	frame[bytes.LastIndex(frame, []byte("second"))-1] = 0x40
+
+	legacy, err := decodeRequestFrame(&DubboCodec{}, frame)
+	assert.Error(t, err)
+	assert.Nil(t, legacy.GetArguments())
+
+	partial, err := decodeRequestFrame(&DubboCodec{KeepPartialRequests: true}, frame)
+	assert.Error(t, err)
+	assert.True(t, partial.IsBroken())
+	assert.Equal(t, "com.demo.HelloService", partial.GetAttachment(PathKey, ""))
+	assert.Equal(t, "sayHello", partial.GetMethodName())
+	assert.Equal(t, []util.Argument{{JavaType: util.JavaString, Value: "first"}}, partial.GetArguments())
+}
+
+func TestDubboCodec_MethodArity(t *testing.T) {
+	d := &DubboCodec{MethodArity: map[string]int{"sayHello": 1}}
+	decoded, ret := decodeRequest(d, encodeRequest(t, d, newTestRequest()))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "world", decoded.GetArguments()[0].GetValue())
+
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{Value: "world"}, {Value: int32(2)}})
+	decoded, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, -1, ret)
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ErrArityMismatch.Error(), decoded.GetData())
+	assert.Nil(t, decoded.GetArguments())
+
+	//methods without an expected arity are not checked
+	req.SetMethodName("sayHelloTwice")
+	_, ret = decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+}
@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync/atomic"
+)
+
+//DecodeConcurrency is a struct which limits how many bodies are decoded at the same time,
+//decodes beyond the limit wait in a queue and are rejected with ErrDecodeBackpressure when it is full.
+//One limiter can be shared by the codecs of all connections
+type DecodeConcurrency struct {
+	slots    chan struct{}
+	maxQueue int32
+	queued   int32
+}
+
+//NewDecodeConcurrency is a function which creates a limiter running at most max decodes,
+//with at most queue decodes waiting. A max below 1 is raised to 1, since no decode could ever run
+func NewDecodeConcurrency(max, queue int) *DecodeConcurrency {
+	if max < 1 {
+		max = 1
+	}
+	return &DecodeConcurrency{slots: make(chan struct{}, max), maxQueue: int32(queue)}
+}
+
+//acquire waits for a free slot, the slot must be released after decode
+func (c *DecodeConcurrency) acquire() error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if atomic.AddInt32(&c.queued, 1) > c.maxQueue {
+		atomic.AddInt32(&c.queued, -1)
+		return ErrDecodeBackpressure
+	}
+	c.slots <- struct{}{}
+	atomic.AddInt32(&c.queued, -1)
+	return nil
+}
+
+func (c *DecodeConcurrency) release() {
+	<-c.slots
+}
+
+//Running is a method which returns the number of decodes running
+func (c *DecodeConcurrency) Running() int {
+	return len(c.slots)
+}
+
+//Queued is a method which returns the number of decodes waiting for a slot
+func (c *DecodeConcurrency) Queued() int {
+	return int(atomic.LoadInt32(&c.queued))
+}
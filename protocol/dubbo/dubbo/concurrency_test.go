@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+//gaugeSerializer records the highest number of reads running at the same time
+type gaugeSerializer struct {
+	jsonSerializer
+	running *int32
+	peak    *int32
+}
+
+func (s gaugeSerializer) ReadObject(b *util.ReadBuffer) (interface{}, error) {
+	n := atomic.AddInt32(s.running, 1)
+	for {
+		peak := atomic.LoadInt32(s.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(s.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(s.running, -1)
+	return s.jsonSerializer.ReadObject(b)
+}
+
+func TestDubboCodec_DecodeConcurrency(t *testing.T) {
+	const gaugeJSON = byte(8)
+	const gaugeType = "Lcom/demo/GaugePayload;"
+	var running, peak int32
+	util.RegisterSerializer(gaugeJSON, gaugeSerializer{running: &running, peak: &peak})
+	util.RegisterArgumentSerialization(gaugeType, gaugeJSON)
+	defer util.RegisterSerializer(gaugeJSON, nil)
+	defer util.RegisterArgumentSerialization(gaugeType, 0)
+
+	d := &DubboCodec{DecodeConcurrency: NewDecodeConcurrency(2, 16)}
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{{JavaType: gaugeType, Value: "payload"}})
+	frame := encodeRequest(t, d, req)
+
+	var wg sync.WaitGroup
+	rets := make([]int, 8)
+	for i := range rets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, rets[i] = decodeRequest(d, frame)
+		}(i)
+	}
+	wg.Wait()
+	for _, ret := range rets {
+		assert.Equal(t, 0, ret)
+	}
+	assert.True(t, peak >= 1 && peak <= 2, "peak %d", peak)
+	assert.Equal(t, 0, d.DecodeConcurrency.Running())
+	assert.Equal(t, 0, d.DecodeConcurrency.Queued())
+}
+
+func TestDubboCodec_DecodeBackpressure(t *testing.T) {
+	d := &DubboCodec{DecodeConcurrency: NewDecodeConcurrency(1, 0)}
+	frame := encodeRequest(t, d, newTestRequest())
+
+	assert.NoError(t, d.DecodeConcurrency.acquire())
+	decoded, ret := decodeRequest(d, frame)
+	assert.Equal(t, -1, ret)
+	assert.Equal(t, ErrDecodeBackpressure.Error(), decoded.GetData())
+	assert.True(t, decoded.IsBroken())
+	assert.Equal(t, ErrDecodeBackpressure, decoded.DecodeError())
+	assert.Equal(t, ErrDecodeBackpressure, d.DecodeConcurrency.acquire())
+
+	d.DecodeConcurrency.release()
+	_, ret = decodeRequest(d, frame)
+	assert.Equal(t, 0, ret)
+}
+
+func TestNewDecodeConcurrency_MaxBelowOne(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		c := NewDecodeConcurrency(max, 0)
+		assert.NoError(t, c.acquire(), "max %d", max)
+		assert.Equal(t, 1, c.Running())
+		assert.Equal(t, ErrDecodeBackpressure, c.acquire(), "max %d", max)
+		c.release()
+		assert.Equal(t, 0, c.Running())
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//maxErrorTemplates bounds the templates of an error response cache, the responses of further errors are
+//encoded for every use
+const maxErrorTemplates = 64
+
+//errorKey is the status and message which make the response of an error
+type errorKey struct {
+	status  byte
+	message string
+}
+
+//ErrorResponseCache is a struct which keeps the encoded responses of common decode failures, such as a bad
+//serialization or an exceeded byte budget, as templates whose request id is patched for every use
+type ErrorResponseCache struct {
+	codec     *DubboCodec
+	mtx       sync.RWMutex
+	templates map[errorKey][]byte
+}
+
+//NewErrorResponseCache is a function which creates a cache of the error responses encoded by codec
+func NewErrorResponseCache(codec *DubboCodec) *ErrorResponseCache {
+	return &ErrorResponseCache{codec: codec, templates: make(map[errorKey][]byte)}
+}
+
+//Response is a method which returns the encoded response reporting err to request id, with the status and
+//message of err. The response of err is encoded the first time, then copied from its template
+func (c *ErrorResponseCache) Response(id int64, err *CodecError) []byte {
+	template := c.template(err)
+	frame := make([]byte, len(template))
+	copy(frame, template)
+	util.Long2bytes(id, frame, 4)
+	return frame
+}
+
+func (c *ErrorResponseCache) template(err *CodecError) []byte {
+	key := errorKey{err.Status, err.Message}
+	c.mtx.RLock()
+	template, ok := c.templates[key]
+	c.mtx.RUnlock()
+	if ok {
+		return template
+	}
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	c.codec.EncodeDubboRsp(NewErrorResponse(0, err.Status, err.Message), &buffer)
+	template = buffer.GetValidData()
+	c.mtx.Lock()
+	if len(c.templates) < maxErrorTemplates {
+		c.templates[key] = template
+	}
+	c.mtx.Unlock()
+	return template
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponseCache_Response(t *testing.T) {
+	d := &DubboCodec{}
+	c := NewErrorResponseCache(d)
+	for _, id := range []int64{7, 1<<31 + 3} {
+		frame := c.Response(id, ErrSerializationNotAllowed)
+		rsp := &DubboRsp{}
+		rsp.Init()
+		bodyLen := 0
+		assert.Equal(t, Success, d.DecodeDubboRsqHead(rsp, frame[:HeaderLength], &bodyLen))
+		var body util.ReadBuffer
+		body.SetBuffer(frame[HeaderLength : HeaderLength+bodyLen])
+		d.DecodeDubboRspBody(&body, rsp)
+		assert.Equal(t, id, rsp.GetID())
+		assert.Equal(t, BadRequest, rsp.GetStatus())
+		assert.Equal(t, ErrSerializationNotAllowed.Message, rsp.GetErrorMsg())
+	}
+
+	//the template is shared and kept unpatched
+	key := errorKey{BadRequest, ErrSerializationNotAllowed.Message}
+	assert.Equal(t, int64(0), util.Bytes2long(c.templates[key], 4))
+	other := c.Response(9, ErrByteBudgetExceeded)
+	assert.Equal(t, ServerThreadPoolExhaustedError, other[3])
+	assert.Len(t, c.templates, 2)
+
+	//errors are told apart by their message, and equal errors share a template
+	c.Response(9, ErrUnknownSerialization)
+	c.Response(9, &CodecError{BadRequest, ErrSerializationNotAllowed.Message})
+	assert.Len(t, c.templates, 3)
+}
+
+func TestErrorResponseCache_Bound(t *testing.T) {
+	c := NewErrorResponseCache(&DubboCodec{})
+	for i := 0; i < 2*maxErrorTemplates; i++ {
+		frame := c.Response(int64(i), &CodecError{BadRequest, strconv.Itoa(i)})
+		assert.Equal(t, int64(i), util.Bytes2long(frame, 4))
+	}
+	assert.Len(t, c.templates, maxErrorTemplates)
+}
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"strconv"
+)
+
+//CodecError is a struct which describes why the codec rejected a frame
+type CodecError struct {
+	Status  byte
+	Message string
+}
+
+func (e *CodecError) Error() string {
+	return e.Message
+}
+
+var (
+	//ErrInvalidCharset is returned in strict mode when an attachment value is not valid UTF-8
+	ErrInvalidCharset = &CodecError{BadRequest, "attachment value is not valid UTF-8"}
+	//ErrUnknownSerialization is returned when no serializer is registered for the serialization of an argument,
+	//or of a frame whose body it can read
+	ErrUnknownSerialization = &CodecError{BadRequest, "serialization is not registered"}
+	//ErrSerializationNotAllowed is returned when the serialization of a frame is not in the allowed list
+	ErrSerializationNotAllowed = &CodecError{BadRequest, "serialization is not allowed"}
+	//ErrSerializationMismatch is returned when the serialization attachment disagrees with the header
+	ErrSerializationMismatch = &CodecError{BadRequest, "serialization attachment does not match header"}
+	//ErrArgumentTooLarge is returned when an argument is larger than the max argument size of codec
+	ErrArgumentTooLarge = &CodecError{BadRequest, "argument exceeds max size"}
+	//ErrUnknownValueType is returned in strict mode when a response has an unknown value type
+	ErrUnknownValueType = &CodecError{BadResponse, "unknown response value type"}
+	//ErrMissingValueType is returned when the body of a response ends before its value type
+	ErrMissingValueType = &CodecError{BadResponse, "response has no value type"}
+	//ErrRequestBodyLength is returned in strict mode when decoding a request body reads more or less than its length
+	ErrRequestBodyLength = &CodecError{BadRequest, "request body length does not match the decoded bytes"}
+	//ErrResponseBodyLength is returned in strict mode when decoding a response body reads more or less than its length
+	ErrResponseBodyLength = &CodecError{BadResponse, "response body length does not match the decoded bytes"}
+	//ErrNegativeBodyLength is returned when the body length of a frame header is negative
+	ErrNegativeBodyLength = &CodecError{BadRequest, "frame body length is negative"}
+	//ErrInvalidHeader is returned when a frame header can not be decoded
+	ErrInvalidHeader = &CodecError{BadResponse, "invalid frame header"}
+	//ErrUnsupportedProtocol is returned when the frame belongs to another protocol such as http/2
+	ErrUnsupportedProtocol = &CodecError{BadRequest, "unsupported protocol"}
+	//ErrQosCommand is returned when a qos command line is decoded as a frame
+	ErrQosCommand = &CodecError{BadRequest, "qos command is not a frame"}
+	//ErrInvalidHandshake is returned when a handshake frame can not be decoded
+	ErrInvalidHandshake = &CodecError{BadRequest, "invalid handshake frame"}
+	//ErrEncodeFailed is returned when a frame can not be encoded
+	ErrEncodeFailed = &CodecError{ClentError, "failed to encode frame"}
+	//ErrFrameTooLarge is returned when the body length of a frame exceeds the max body size of its frame reader
+	ErrFrameTooLarge = &CodecError{BadRequest, "frame body exceeds max size"}
+	//ErrFrameLimit is returned when a frame reader has read its max number of frames
+	ErrFrameLimit = &CodecError{ClentError, "frame limit reached"}
+	//ErrSessionDraining is returned when a frame is sent on a draining session
+	ErrSessionDraining = &CodecError{ClentError, "codec session is draining"}
+	//ErrDuplicateRequestID is returned when a request shares its id with a request still in flight
+	ErrDuplicateRequestID = &CodecError{ClentError, "request id is already in flight"}
+	//ErrUnexpectedInterface is returned when the interface of a request is not the expected interface of codec
+	ErrUnexpectedInterface = &CodecError{ServiceNotFound, "interface is not the expected interface"}
+	//ErrArityMismatch is returned when a request declares another number of arguments than its method expects
+	ErrArityMismatch = &CodecError{BadRequest, "argument count does not match the method"}
+	//ErrChaosInjected is the decode error a chaos hook can inject
+	ErrChaosInjected = &CodecError{ServerError, "decode failure injected by chaos hook"}
+	//ErrDecodeBackpressure is returned when the queue of the decode concurrency limiter is full
+	ErrDecodeBackpressure = &CodecError{ServerThreadPoolExhaustedError, "too many concurrent decodes"}
+	//ErrByteBudgetExceeded is returned when the body of a frame does not fit in the byte budget of its session
+	ErrByteBudgetExceeded = &CodecError{ServerThreadPoolExhaustedError, "session byte budget exceeded"}
+)
+
+//FrameError is a struct which tells which frame of a batch failed to decode and why
+type FrameError struct {
+	Index int
+	Err   error
+}
+
+func (e *FrameError) Error() string {
+	return "frame " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+}
+
+//errorStatus returns the status of a codec error, ServerError for other errors
+func errorStatus(err error) byte {
+	if e, ok := err.(*CodecError); ok {
+		return e.Status
+	}
+	return ServerError
+}
+
+//headerError converts the return code of a header decoder into an error
+func headerError(ret int) error {
+	switch ret {
+	case SerializationNotAllowed:
+		return ErrSerializationNotAllowed
+	case InvalidSerialization:
+		return ErrUnknownSerialization
+	case UnsupportedProtocol:
+		return ErrUnsupportedProtocol
+	case QosCommandLine:
+		return ErrQosCommand
+	case InvalidBodyLength:
+		return ErrNegativeBodyLength
+	}
+	return ErrInvalidHeader
+}
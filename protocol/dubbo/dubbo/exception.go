@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//maxCauseDepth limits how many causes of an exception are converted
+const maxCauseDepth = 16
+
+//DubboException is a struct which holds a java Throwable decoded from a response
+type DubboException struct {
+	Message    string
+	StackTrace []StackFrame
+	Cause      *DubboException
+	Suppressed []*DubboException
+	localized  string
+}
+
+//StackFrame is a struct which holds a java StackTraceElement, Line is negative when it is unknown,
+//-2 meaning a native method
+type StackFrame struct {
+	Class  string
+	Method string
+	File   string
+	Line   int
+}
+
+func (e *DubboException) Error() string {
+	return e.Message
+}
+
+//GetLocalizedMessage is a method which returns the localized message of exception, carried by the exceptions
+//which serialize a localizedMessage field, it falls back to the message like java does
+func (e *DubboException) GetLocalizedMessage() string {
+	if e.localized != "" {
+		return e.localized
+	}
+	return e.Message
+}
+
+//NewDubboException is a function which converts a decoded java Throwable into a DubboException,
+//it returns nil if v is not a decoded object
+func NewDubboException(v interface{}) *DubboException {
+	return toDubboException(util.ToJSONValue(v), 0)
+}
+
+//isThrowable reports whether v is a decoded java Throwable, which has a detail message besides its
+//stack trace, cause or suppressed exceptions. The class name is not kept by the decoder
+func isThrowable(v interface{}) bool {
+	fields, ok := util.ToJSONValue(v).(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if _, ok := fields["detailMessage"]; !ok {
+		return false
+	}
+	for _, name := range []string{"stackTrace", "cause", "suppressedExceptions"} {
+		if _, ok := fields[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toDubboException(v interface{}, depth int) *DubboException {
+	fields, ok := v.(map[string]interface{})
+	if !ok || depth > maxCauseDepth {
+		return nil
+	}
+	e := &DubboException{}
+	e.Message, _ = fields["detailMessage"].(string)
+	e.localized, _ = fields["localizedMessage"].(string)
+	e.StackTrace = toStackFrames(fields["stackTrace"])
+	if cause, ok := fields["cause"].(map[string]interface{}); ok {
+		e.Cause = toDubboException(cause, depth+1)
+	}
+	suppressed, _ := fields["suppressedExceptions"].([]interface{})
+	for _, item := range suppressed {
+		if s := toDubboException(item, depth+1); s != nil {
+			e.Suppressed = append(e.Suppressed, s)
+		}
+	}
+	return e
+}
+
+//toStackFrames converts the decoded StackTraceElement array of a Throwable
+func toStackFrames(v interface{}) []StackFrame {
+	elems, _ := v.([]interface{})
+	var frames []StackFrame
+	for _, elem := range elems {
+		fields, ok := elem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		frame := StackFrame{Line: -1}
+		frame.Class, _ = fields["declaringClass"].(string)
+		frame.Method, _ = fields["methodName"].(string)
+		frame.File, _ = fields["fileName"].(string)
+		switch line := fields["lineNumber"].(type) {
+		case int32:
+			frame.Line = int(line)
+		case int64:
+			frame.Line = int(line)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type throwable struct {
+	DetailMessage        string
+	SuppressedExceptions []interface{}
+}
+
+func TestDubboRsp_GetDubboException(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(1)
+	rsp.SetException(throwable{"write failed", []interface{}{throwable{"close failed", []interface{}{}}}})
+
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	e := decoded.GetDubboException()
+	assert.NotNil(t, e)
+	assert.Equal(t, "write failed", e.Error())
+	assert.Nil(t, e.Cause)
+	assert.Len(t, e.Suppressed, 1)
+	assert.Equal(t, "close failed", e.Suppressed[0].Message)
+	assert.Empty(t, e.Suppressed[0].Suppressed)
+
+	rsp = &DubboRsp{}
+	rsp.Init()
+	rsp.SetValue("hello")
+	decoded, _ = decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Nil(t, decoded.GetDubboException())
+}
+
+//stackTraceElement has the serialized fields of java.lang.StackTraceElement
+type stackTraceElement struct {
+	DeclaringClass string
+	MethodName     string
+	FileName       string
+	LineNumber     int32
+}
+
+type tracedThrowable struct {
+	DetailMessage string
+	StackTrace    []interface{}
+}
+
+func TestDubboRsp_StackTrace(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetException(tracedThrowable{"write failed", []interface{}{
+		stackTraceElement{"com.demo.FileStore", "write", "FileStore.java", 42},
+		stackTraceElement{"java.io.FileOutputStream", "writeBytes", "FileOutputStream.java", 31},
+		stackTraceElement{"com.demo.HelloServiceImpl", "sayHello", "HelloServiceImpl.java", 7},
+	}})
+
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	e := decoded.GetDubboException()
+	assert.NotNil(t, e)
+	assert.Equal(t, []StackFrame{
+		{"com.demo.FileStore", "write", "FileStore.java", 42},
+		{"java.io.FileOutputStream", "writeBytes", "FileOutputStream.java", 31},
+		{"com.demo.HelloServiceImpl", "sayHello", "HelloServiceImpl.java", 7},
+	}, e.StackTrace)
+}
+
+func TestDubboRsp_GetThrowableValue(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetValue(throwable{"last failure", []interface{}{}})
+
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, Ok, decoded.GetStatus())
+	assert.Nil(t, decoded.GetDubboException())
+	e := decoded.GetThrowableValue()
+	assert.NotNil(t, e)
+	assert.Equal(t, "last failure", e.Message)
+
+	rsp.SetValue(map[string]interface{}{"detailMessage": "not a throwable"})
+	decoded, _ = decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Nil(t, decoded.GetThrowableValue())
+}
+
+func TestDubboCodec_PreserveTransportStatus(t *testing.T) {
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetException(throwable{"write failed", []interface{}{}})
+	frame := encodeResponse(t, &DubboCodec{}, rsp)
+
+	legacy, ret := decodeResponse(&DubboCodec{}, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, ServiceError, legacy.GetStatus())
+	assert.Nil(t, legacy.GetException())
+	assert.Equal(t, "write failed", legacy.GetDubboException().Message)
+
+	d := &DubboCodec{PreserveTransportStatus: true}
+	preserved, ret := decodeResponse(d, frame)
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, Ok, preserved.GetStatus())
+	assert.Nil(t, preserved.GetValue())
+	assert.Equal(t, "write failed", preserved.GetDubboException().Message)
+	assert.Nil(t, preserved.GetThrowableValue())
+
+	reencoded, _ := decodeResponse(&DubboCodec{}, encodeResponse(t, d, preserved))
+	assert.Equal(t, ServiceError, reencoded.GetStatus())
+}
+
+type localizedThrowable struct {
+	DetailMessage    string
+	LocalizedMessage string
+	StackTrace       []interface{}
+}
+
+func TestDubboException_GetLocalizedMessage(t *testing.T) {
+	d := &DubboCodec{}
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetException(localizedThrowable{"file not found", "fichier introuvable", []interface{}{}})
+	decoded, ret := decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, 0, ret)
+	e := decoded.GetDubboException()
+	assert.NotNil(t, e)
+	assert.Equal(t, "file not found", e.Message)
+	assert.Equal(t, "fichier introuvable", e.GetLocalizedMessage())
+
+	rsp.SetException(tracedThrowable{"write failed", []interface{}{}})
+	decoded, _ = decodeResponse(d, encodeResponse(t, d, rsp))
+	assert.Equal(t, "write failed", decoded.GetDubboException().GetLocalizedMessage())
+}
@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+	"time"
+)
+
+//FlowStats is a struct which describes the frames and bytes passed through a connection
+type FlowStats struct {
+	Frames          int64
+	Bytes           int64
+	FramesPerSecond float64
+	BytesPerSecond  float64
+}
+
+//FlowMeter is a struct which aggregates the frames and bytes of one connection,
+//every Sample reports the throughput since the previous sample to the callback
+type FlowMeter struct {
+	mtx         sync.Mutex
+	frames      int64
+	bytes       int64
+	lastFrames  int64
+	lastBytes   int64
+	lastSampled time.Time
+	onSample    func(FlowStats)
+}
+
+//NewFlowMeter is a function which creates a flow meter, onSample may be nil
+func NewFlowMeter(onSample func(FlowStats)) *FlowMeter {
+	return &FlowMeter{lastSampled: time.Now(), onSample: onSample}
+}
+
+//Record is a method which counts one frame of size bytes
+func (m *FlowMeter) Record(size int) {
+	m.mtx.Lock()
+	m.frames++
+	m.bytes += int64(size)
+	m.mtx.Unlock()
+}
+
+//Sample is a method which computes the throughput since the previous sample and passes it to the callback
+func (m *FlowMeter) Sample() FlowStats {
+	m.mtx.Lock()
+	now := time.Now()
+	stats := FlowStats{Frames: m.frames, Bytes: m.bytes}
+	if elapsed := now.Sub(m.lastSampled).Seconds(); elapsed > 0 {
+		stats.FramesPerSecond = float64(m.frames-m.lastFrames) / elapsed
+		stats.BytesPerSecond = float64(m.bytes-m.lastBytes) / elapsed
+	}
+	m.lastFrames, m.lastBytes, m.lastSampled = m.frames, m.bytes, now
+	m.mtx.Unlock()
+	if m.onSample != nil {
+		m.onSample(stats)
+	}
+	return stats
+}
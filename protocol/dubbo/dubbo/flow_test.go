@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowMeter_Session(t *testing.T) {
+	var sampled []FlowStats
+	meter := NewFlowMeter(func(stats FlowStats) {
+		sampled = append(sampled, stats)
+	})
+	var out bytes.Buffer
+	s := NewCodecSession(&DubboCodec{}, &out)
+	s.SetFlowMeter(meter)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Send(newTestRequest()))
+	}
+	time.Sleep(time.Millisecond)
+
+	stats := meter.Sample()
+	assert.Equal(t, int64(3), stats.Frames)
+	assert.Equal(t, int64(out.Len()), stats.Bytes)
+	assert.True(t, stats.FramesPerSecond > 0)
+	assert.True(t, stats.BytesPerSecond > 0)
+
+	assert.NoError(t, s.Send(newTestRequest()))
+	stats = meter.Sample()
+	assert.Equal(t, int64(4), stats.Frames)
+	assert.Equal(t, int64(out.Len()), stats.Bytes)
+	assert.Len(t, sampled, 2)
+	assert.Equal(t, stats, sampled[1])
+}
+
+func TestFlowMeter_SessionResponses(t *testing.T) {
+	meter := NewFlowMeter(nil)
+	d := &DubboCodec{}
+	s := NewCodecSession(d, &bytes.Buffer{})
+	s.SetFlowMeter(meter)
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetValue("hello")
+	frame := encodeResponse(t, d, rsp)
+	for i := 0; i < 2; i++ {
+		_, err := decodeSessionResponse(s, frame)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.Send(newTestRequest()))
+
+	stats := meter.Sample()
+	assert.Equal(t, int64(3), stats.Frames)
+	assert.True(t, stats.Bytes > int64(2*len(frame)))
+}
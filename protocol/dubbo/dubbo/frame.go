@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"io"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//Frame is a struct which holds the header and body of one dubbo frame
+type Frame struct {
+	Header []byte
+	Body   []byte
+}
+
+//Framing is the way a frame reader finds the frames in a stream
+type Framing int
+
+const (
+	//FramingMagic expects every frame to start with the dubbo magic number
+	FramingMagic Framing = iota
+	//FramingLengthPrefixed only relies on the body length in the header, for frames tunneled over
+	//transports which do the framing and may not keep the magic number
+	FramingLengthPrefixed
+)
+
+//DefaultMaxBodySize is the max body size of frame readers, the default payload limit of dubbo
+const DefaultMaxBodySize = 8 << 20
+
+//FrameReader is a struct which splits a stream into dubbo frames
+type FrameReader struct {
+	reader      io.Reader
+	maxFrames   int
+	frames      int
+	framing     Framing
+	maxBodySize int
+}
+
+//NewFrameReader is a function which creates a frame reader on r
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{reader: r, maxBodySize: DefaultMaxBodySize}
+}
+
+//SetMaxFrames is a method which makes the reader return ErrFrameLimit after n frames, 0 means no limit
+func (f *FrameReader) SetMaxFrames(n int) {
+	f.maxFrames = n
+}
+
+//SetMaxBodySize is a method which makes the reader return ErrFrameTooLarge for a frame whose body is longer
+//than n bytes before the body is read, DefaultMaxBodySize by default and 0 means no limit
+func (f *FrameReader) SetMaxBodySize(n int) {
+	f.maxBodySize = n
+}
+
+//SetFraming is a method which sets how frames are found in the stream, FramingMagic by default
+func (f *FrameReader) SetFraming(framing Framing) {
+	f.framing = framing
+}
+
+//ReadFrame is a method which reads the next frame from stream. It returns io.EOF when the stream is closed
+//cleanly between two frames, such as after a read-only event, and io.ErrUnexpectedEOF when it is closed in
+//the middle of a frame
+func (f *FrameReader) ReadFrame() (*Frame, error) {
+	if f.maxFrames > 0 && f.frames >= f.maxFrames {
+		return nil, ErrFrameLimit
+	}
+	header := make([]byte, HeaderLength)
+	if _, err := io.ReadFull(f.reader, header); err != nil {
+		return nil, err
+	}
+	if f.framing == FramingMagic && (header[0] != MagicHigh || header[1] != MagicLow) {
+		return nil, ErrInvalidHeader
+	}
+	bodyLen := int(util.Bytes2int(header, 12))
+	if bodyLen < 0 {
+		return nil, ErrInvalidHeader
+	}
+	if f.maxBodySize > 0 && bodyLen > f.maxBodySize {
+		return nil, ErrFrameTooLarge
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(f.reader, body); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	f.frames++
+	return &Frame{header, body}, nil
+}
+
+//MessageSource returns the payload of the next message of a message based transport, such as the binary
+//messages of a WebSocket connection, and io.EOF when the transport is closed
+type MessageSource func() ([]byte, error)
+
+//MessageReader is a struct which reassembles the payloads of a message based transport into a stream, so that
+//frames split across messages or coalesced within one message can be read by a FrameReader
+type MessageReader struct {
+	source  MessageSource
+	pending []byte
+}
+
+//NewMessageReader is a function which creates a reader on the payloads of source
+func NewMessageReader(source MessageSource) *MessageReader {
+	return &MessageReader{source: source}
+}
+
+//NewMessageFrameReader is a function which creates a frame reader on the payloads of source, such as Dubbo
+//tunneled over WebSocket
+func NewMessageFrameReader(source MessageSource) *FrameReader {
+	return NewFrameReader(NewMessageReader(source))
+}
+
+//Read is a method which reads the pending payload, the next message is read once the payload is consumed
+func (r *MessageReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		msg, err := r.source()
+		if err != nil {
+			return 0, err
+		}
+		r.pending = msg
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,154 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+//repeatReader repeats a frame forever
+type repeatReader struct {
+	frame []byte
+	pos   int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.frame[r.pos:])
+	r.pos = (r.pos + n) % len(r.frame)
+	return n, nil
+}
+
+func TestFrameReader_SetMaxFrames(t *testing.T) {
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, newTestRequest())
+	reader := NewFrameReader(&repeatReader{frame: frame})
+	reader.SetMaxFrames(3)
+
+	count := 0
+	for {
+		f, err := reader.ReadFrame()
+		if err != nil {
+			assert.Equal(t, ErrFrameLimit, err)
+			break
+		}
+		assert.Equal(t, frame, append(f.Header, f.Body...))
+		count++
+	}
+	assert.Equal(t, 3, count)
+	_, err := reader.ReadFrame()
+	assert.Equal(t, ErrFrameLimit, err)
+}
+
+func TestFrameReader_LengthPrefixed(t *testing.T) {
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, newTestRequest())
+	tunneled := append([]byte{0, 0}, frame[2:]...)
+	stream := append(append([]byte{}, tunneled...), tunneled...)
+
+	_, err := NewFrameReader(bytes.NewReader(stream)).ReadFrame()
+	assert.Equal(t, ErrInvalidHeader, err)
+
+	reader := NewFrameReader(bytes.NewReader(stream))
+	reader.SetFraming(FramingLengthPrefixed)
+	for i := 0; i < 2; i++ {
+		f, err := reader.ReadFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, tunneled[:HeaderLength], f.Header)
+		assert.Equal(t, frame[HeaderLength:], f.Body)
+	}
+}
+
+func TestFrameReader_Close(t *testing.T) {
+	d := &DubboCodec{}
+	event := encodeRequest(t, d, NewReadOnlyEvent())
+	frame := encodeRequest(t, d, newTestRequest())
+
+	//closed after a read-only event
+	reader := NewFrameReader(bytes.NewReader(event))
+	f, err := reader.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, event, append(f.Header, f.Body...))
+	_, err = reader.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+
+	//closed in the middle of the header, at the end of the header and in the middle of the body
+	for _, size := range []int{HeaderLength / 2, HeaderLength, len(frame) - 1} {
+		stream := append(append([]byte{}, event...), frame[:size]...)
+		reader = NewFrameReader(bytes.NewReader(stream))
+		_, err = reader.ReadFrame()
+		assert.NoError(t, err)
+		_, err = reader.ReadFrame()
+		assert.Equal(t, io.ErrUnexpectedEOF, err, size)
+	}
+}
+
+func TestFrameReader_SetMaxBodySize(t *testing.T) {
+	frame := encodeRequest(t, &DubboCodec{}, newTestRequest())
+	bodyLen := len(frame) - HeaderLength
+	reader := NewFrameReader(&repeatReader{frame: frame})
+	reader.SetMaxBodySize(bodyLen)
+	_, err := reader.ReadFrame()
+	assert.NoError(t, err)
+	reader.SetMaxBodySize(bodyLen - 1)
+	_, err = reader.ReadFrame()
+	assert.Equal(t, ErrFrameTooLarge, err)
+
+	//a peer declaring a 2 GiB body is rejected by default before anything is allocated
+	huge := append([]byte{}, frame[:HeaderLength]...)
+	util.Int2bytes(1<<31-1, huge, 12)
+	_, err = NewFrameReader(bytes.NewReader(huge)).ReadFrame()
+	assert.Equal(t, ErrFrameTooLarge, err)
+}
+
+//messages returns a message source delivering msgs, then io.EOF
+func messages(msgs ...[]byte) MessageSource {
+	return func() ([]byte, error) {
+		if len(msgs) == 0 {
+			return nil, io.EOF
+		}
+		msg := msgs[0]
+		msgs = msgs[1:]
+		return msg, nil
+	}
+}
+
+func TestMessageFrameReader(t *testing.T) {
+	d := &DubboCodec{}
+	frame := encodeRequest(t, d, newTestRequest())
+	event := encodeRequest(t, d, NewReadOnlyEvent())
+
+	//a frame split across two messages, then an empty message and two frames coalesced in one message
+	split := HeaderLength + 5
+	reader := NewMessageFrameReader(messages(frame[:split], frame[split:], nil, append(append([]byte{}, event...), frame...)))
+	for _, expected := range [][]byte{frame, event, frame} {
+		f, err := reader.ReadFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, append(f.Header, f.Body...))
+	}
+	_, err := reader.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+
+	reader = NewMessageFrameReader(messages(frame[:split]))
+	_, err = reader.ReadFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
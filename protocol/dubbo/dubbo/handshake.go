@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//Constants of handshake frames
+const (
+	HandshakeHeaderLength = 8
+	HandshakeMagic        = 0xdacc
+	HandshakeMagicLow     = byte(0xcc)
+	HandshakeVersion      = byte(1)
+)
+
+//Handshake is a struct which describes what a mesher peer supports, it is exchanged once before the rpc
+//frames of mesher-to-mesher links and must never be sent to plain java peers, which do not understand it
+type Handshake struct {
+	Version        string
+	Serializations []byte
+	Compressions   []string
+}
+
+//IsHandshake is a function which reports whether head starts a handshake frame rather than a rpc frame
+func IsHandshake(head []byte) bool {
+	return len(head) >= 2 && head[0] == MagicHigh && head[1] == HandshakeMagicLow
+}
+
+//EncodeHandshake is a function which encodes h into a handshake frame, the header holds the magic number,
+//the handshake version and the body length, the body holds the fields of h as hessian values
+func EncodeHandshake(h *Handshake, buffer *util.WriteBuffer) error {
+	header := make([]byte, HandshakeHeaderLength)
+	util.Short2bytes(HandshakeMagic, header, 0)
+	header[2] = HandshakeVersion
+	buffer.WriteIndex(HandshakeHeaderLength)
+
+	values := []interface{}{h.Version, int32(len(h.Serializations))}
+	for _, id := range h.Serializations {
+		values = append(values, int32(id))
+	}
+	values = append(values, int32(len(h.Compressions)))
+	for _, name := range h.Compressions {
+		values = append(values, name)
+	}
+	for _, v := range values {
+		if err := buffer.WriteObject(v); err != nil {
+			return err
+		}
+	}
+
+	len := buffer.WrittenBytes() - HandshakeHeaderLength
+	util.Int2bytes(len, header, 4)
+	buffer.WriteIndex(0)
+	buffer.WriteBytes(header)
+	buffer.WriteIndex(HandshakeHeaderLength + len)
+	return nil
+}
+
+//DecodeHandshake is a function which decodes a handshake frame and records it as the peer handshake of
+//session, session may be nil
+func DecodeHandshake(frame []byte, session *CodecSession) (*Handshake, error) {
+	if len(frame) < HandshakeHeaderLength || !IsHandshake(frame) || frame[2] != HandshakeVersion {
+		return nil, ErrInvalidHandshake
+	}
+	bodyLen := int(util.Bytes2int(frame, 4))
+	if bodyLen != len(frame)-HandshakeHeaderLength {
+		return nil, ErrInvalidHandshake
+	}
+	var bodyBuf util.ReadBuffer
+	bodyBuf.SetBuffer(frame[HandshakeHeaderLength:])
+
+	h := &Handshake{}
+	var err error
+	if h.Version, err = bodyBuf.ReadStringE(); err != nil {
+		return nil, ErrInvalidHandshake
+	}
+	ids, err := readHandshakeCount(&bodyBuf)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < ids; i++ {
+		id, err := readHandshakeCount(&bodyBuf)
+		if err != nil {
+			return nil, err
+		}
+		h.Serializations = append(h.Serializations, byte(id))
+	}
+	names, err := readHandshakeCount(&bodyBuf)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < names; i++ {
+		name, err := bodyBuf.ReadStringE()
+		if err != nil {
+			return nil, ErrInvalidHandshake
+		}
+		h.Compressions = append(h.Compressions, name)
+	}
+	if session != nil {
+		session.SetPeerHandshake(h)
+	}
+	return h, nil
+}
+
+//readHandshakeCount reads a non negative int of a handshake body
+func readHandshakeCount(bodyBuf *util.ReadBuffer) (int, error) {
+	obj, err := bodyBuf.ReadObject()
+	if err != nil {
+		return 0, ErrInvalidHandshake
+	}
+	n, ok := obj.(int32)
+	if !ok || n < 0 {
+		return 0, ErrInvalidHandshake
+	}
+	return int(n), nil
+}
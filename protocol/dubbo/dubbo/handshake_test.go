@@ -0,0 +1,51 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandshake_RoundTrip(t *testing.T) {
+	sent := &Handshake{
+		Version:        DubboVersion,
+		Serializations: []byte{Hessian2, 12},
+		Compressions:   []string{"gzip", "snappy"},
+	}
+	var out bytes.Buffer
+	local := NewCodecSession(&DubboCodec{}, &out)
+	assert.NoError(t, local.SendHandshake(sent))
+	assert.True(t, IsHandshake(out.Bytes()))
+
+	remote := NewCodecSession(&DubboCodec{}, &bytes.Buffer{})
+	assert.Nil(t, remote.PeerHandshake())
+	received, err := DecodeHandshake(out.Bytes(), remote)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, received)
+	assert.Equal(t, sent, remote.PeerHandshake())
+
+	frame := encodeRequest(t, &DubboCodec{}, newTestRequest())
+	assert.False(t, IsHandshake(frame))
+	_, err = DecodeHandshake(frame, nil)
+	assert.Equal(t, ErrInvalidHandshake, err)
+	_, err = DecodeHandshake(out.Bytes()[:out.Len()-1], nil)
+	assert.Equal(t, ErrInvalidHandshake, err)
+}
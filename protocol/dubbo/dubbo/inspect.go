@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//frameSummary is the json rendering of a decoded frame
+type frameSummary struct {
+	ID            int64             `json:"id"`
+	Request       bool              `json:"request"`
+	TwoWay        bool              `json:"twoWay"`
+	Event         bool              `json:"event"`
+	Serialization byte              `json:"serialization"`
+	Status        byte              `json:"status,omitempty"`
+	Interface     string            `json:"interface,omitempty"`
+	Method        string            `json:"method,omitempty"`
+	Args          []interface{}     `json:"args,omitempty"`
+	Value         interface{}       `json:"value,omitempty"`
+	Exception     interface{}       `json:"exception,omitempty"`
+	ErrorMsg      string            `json:"errorMsg,omitempty"`
+	Attachments   map[string]string `json:"attachments,omitempty"`
+}
+
+//Result is a struct which holds a decoded frame, Request is set for request frames and Response otherwise
+type Result struct {
+	Request  *Request
+	Response *ResponseResult
+}
+
+//DecodeFrame is a function which decodes a whole request or response frame, header and body
+func DecodeFrame(frame []byte) (*Result, error) {
+	if len(frame) < HeaderLength || frame[0] != MagicHigh || frame[1] != MagicLow {
+		return nil, ErrInvalidHeader
+	}
+	bodyLen := int(util.Bytes2int(frame, 12))
+	if bodyLen < 0 {
+		return nil, ErrInvalidHeader
+	}
+	if len(frame) < HeaderLength+bodyLen {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength : HeaderLength+bodyLen])
+	codec := &DubboCodec{}
+	var err error
+	result := &Result{}
+	if frame[2]&FlagRequest != 0 {
+		result.Request, err = codec.DecodeRequest(frame[:HeaderLength], &body)
+	} else {
+		result.Response, err = codec.DecodeResponse(frame[:HeaderLength], &body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//BatchDecode is a function which decodes every frame of frames, it does not stop at the first failure.
+//The results and errors are aligned with frames, the errors of failed frames are FrameError and the
+//others are nil
+func BatchDecode(frames [][]byte) ([]Result, []error) {
+	results := make([]Result, len(frames))
+	errs := make([]error, len(frames))
+	for i, frame := range frames {
+		result, err := DecodeFrame(frame)
+		if err != nil {
+			errs[i] = &FrameError{i, err}
+			continue
+		}
+		results[i] = *result
+	}
+	return results, errs
+}
+
+//FrameToJSON is a function which decodes a whole request or response frame, header and body, and renders a
+//json summary of it for troubleshooting captured traffic. The arguments of requests are passed through the
+//redactor and attachments through the attachment redactor, with no interface and method for responses
+func FrameToJSON(frame []byte) (string, error) {
+	result, err := DecodeFrame(frame)
+	if err != nil {
+		return "", err
+	}
+	flag := frame[2]
+	summary := &frameSummary{
+		Request:       flag&FlagRequest != 0,
+		TwoWay:        flag&FlagTwoWay != 0,
+		Event:         flag&FlagEvent != 0,
+		Serialization: flag & SerializationMask,
+	}
+	if result.Request != nil {
+		summarizeRequest(summary, result.Request)
+	} else {
+		summarizeResponse(summary, result.Response)
+	}
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+//summarizeRequest fills summary with a decoded request
+func summarizeRequest(summary *frameSummary, req *Request) {
+	summary.ID = req.GetMsgID()
+	if req.IsEvent() {
+		return
+	}
+	summary.Interface = req.GetAttachment(PathKey, "")
+	summary.Method = req.GetMethodName()
+	for _, value := range redactedArguments(req) {
+		summary.Args = append(summary.Args, util.ToJSONValue(value))
+	}
+	summary.Attachments = redactedAttachments(summary.Interface, summary.Method, req.GetAttachments())
+}
+
+//summarizeResponse fills summary with a decoded response
+func summarizeResponse(summary *frameSummary, result *ResponseResult) {
+	summary.ID = result.ID
+	summary.Status = result.Status
+	summary.Value = util.ToJSONValue(result.Value)
+	summary.Exception = util.ToJSONValue(result.Exception)
+	summary.ErrorMsg = result.ErrorMsg
+	summary.Attachments = redactedAttachments("", "", result.Attachments)
+}
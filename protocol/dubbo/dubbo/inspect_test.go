@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameToJSON(t *testing.T) {
+	d := &DubboCodec{}
+	req := newTestRequest()
+	req.SetMsgID(3)
+	out, err := FrameToJSON(encodeRequest(t, d, req))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":3,"request":true,"twoWay":true,"event":false,"serialization":2,`+
+		`"interface":"com.demo.HelloService","method":"sayHello","args":["world"],`+
+		`"attachments":{"path":"com.demo.HelloService"}}`, out)
+
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(3)
+	rsp.SetValue(map[string]interface{}{"name": "world"})
+	out, err = FrameToJSON(encodeResponse(t, d, rsp))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":3,"request":false,"twoWay":false,"event":false,"serialization":2,`+
+		`"status":20,"value":{"name":"world"}}`, out)
+
+	frame := encodeRequest(t, d, req)
+	_, err = FrameToJSON(frame[:len(frame)-1])
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = FrameToJSON(frame[2:])
+	assert.Equal(t, ErrInvalidHeader, err)
+}
+
+func TestFrameToJSON_Redactor(t *testing.T) {
+	SetRedactor(func(interfaceName, method string, argIndex int, value interface{}) interface{} {
+		return "***"
+	})
+	defer SetRedactor(nil)
+	SetAttachmentRedactor(func(interfaceName, method, key, value string) string {
+		if key == "token" {
+			return "***"
+		}
+		return value
+	})
+	defer SetAttachmentRedactor(nil)
+
+	req := newTestRequest()
+	req.SetMsgID(3)
+	req.SetAttachment("token", "secret")
+	out, err := FrameToJSON(encodeRequest(t, &DubboCodec{}, req))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":3,"request":true,"twoWay":true,"event":false,"serialization":2,`+
+		`"interface":"com.demo.HelloService","method":"sayHello","args":["***"],`+
+		`"attachments":{"path":"com.demo.HelloService","token":"***"}}`, out)
+	assert.Equal(t, "secret", req.GetAttachment("token", ""))
+}
+
+func TestBatchDecode(t *testing.T) {
+	d := &DubboCodec{}
+	req := encodeRequest(t, d, newTestRequest())
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetValue("hello")
+	badMagic := append([]byte{0xca, 0xfe}, req[2:]...)
+
+	results, errs := BatchDecode([][]byte{req, encodeResponse(t, d, rsp), badMagic, req[:len(req)-1]})
+	assert.Len(t, results, 4)
+	assert.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.Equal(t, "sayHello", results[0].Request.GetMethodName())
+	assert.Nil(t, results[0].Response)
+	assert.NoError(t, errs[1])
+	assert.Equal(t, "hello", results[1].Response.Value)
+	assert.Equal(t, &FrameError{2, ErrInvalidHeader}, errs[2])
+	assert.Equal(t, "frame 2: invalid frame header", errs[2].Error())
+	assert.Equal(t, &FrameError{3, io.ErrUnexpectedEOF}, errs[3])
+	assert.Equal(t, Result{}, results[3])
+}
@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//Multiplexer is a struct which rewrites the ids of requests sharing one upstream connection
+//and restores the original ids on their responses
+type Multiplexer struct {
+	mtx     sync.Mutex
+	pending map[int64]int64 //rewritten id to original id
+}
+
+//NewMultiplexer is a function which creates a multiplexer
+func NewMultiplexer() *Multiplexer {
+	return &Multiplexer{pending: make(map[int64]int64)}
+}
+
+//EncodeDubboReq is a method which encodes req with a connection unique id, the id of req itself is kept
+func (m *Multiplexer) EncodeDubboReq(codec *DubboCodec, req *Request, buffer *util.WriteBuffer) int {
+	original := req.GetMsgID()
+	id := GenerateMsgID()
+	req.SetMsgID(id)
+	ret := codec.EncodeDubboReq(req, buffer)
+	req.SetMsgID(original)
+	if ret == 0 && req.IsTwoWay() && !req.IsEvent() {
+		m.mtx.Lock()
+		m.pending[id] = original
+		m.mtx.Unlock()
+	}
+	return ret
+}
+
+//DecodeDubboRsqHead is a method which decodes the response header and restores the original request id
+func (m *Multiplexer) DecodeDubboRsqHead(codec *DubboCodec, rsp *DubboRsp, header []byte, bodyLen *int) int {
+	ret := codec.DecodeDubboRsqHead(rsp, header, bodyLen)
+	if ret == Success {
+		m.Restore(rsp)
+	}
+	return ret
+}
+
+//Restore is a method which sets the original request id on rsp,
+//it returns false if rsp answers no request encoded by multiplexer
+func (m *Multiplexer) Restore(rsp *DubboRsp) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	original, ok := m.pending[rsp.GetID()]
+	if !ok {
+		return false
+	}
+	delete(m.pending, rsp.GetID())
+	rsp.SetID(original)
+	return true
+}
+
+//Pending is a method which returns the number of requests waiting for response
+func (m *Multiplexer) Pending() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return len(m.pending)
+}
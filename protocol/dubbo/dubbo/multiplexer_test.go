@@ -0,0 +1,75 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiplexer_Concurrent(t *testing.T) {
+	const clients = 32
+	d := &DubboCodec{}
+	m := NewMultiplexer()
+	frames := make([][]byte, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := newTestRequest()
+			req.SetMsgID(int64(i % 4)) //clients reuse the same ids
+			var buffer util.WriteBuffer
+			buffer.Init(0)
+			assert.Equal(t, 0, m.EncodeDubboReq(d, req, &buffer))
+			assert.Equal(t, int64(i%4), req.GetMsgID())
+			frames[i] = buffer.GetValidData()
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, clients, m.Pending())
+
+	wireIDs := make(map[int64]bool)
+	for _, frame := range frames {
+		wireIDs[util.Bytes2long(frame, 4)] = true
+	}
+	assert.Len(t, wireIDs, clients)
+
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rsp := &DubboRsp{}
+			rsp.Init()
+			rsp.SetID(util.Bytes2long(frames[i], 4))
+			rsp.SetValue("hello")
+			frame := encodeResponse(t, d, rsp)
+
+			decoded := &DubboRsp{}
+			bodyLen := 0
+			assert.Equal(t, Success, m.DecodeDubboRsqHead(d, decoded, frame[:HeaderLength], &bodyLen))
+			assert.Equal(t, int64(i%4), decoded.GetID())
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 0, m.Pending())
+	assert.False(t, m.Restore(&DubboRsp{}))
+}
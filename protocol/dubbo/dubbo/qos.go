@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+//QosPrompt is the prompt dubbo writes after the output of a qos command
+const QosPrompt = "dubbo>"
+
+//QosCommand is a struct which holds a management command of dubbo qos, which arrives as a text line
+//on the dubbo port instead of a frame
+type QosCommand struct {
+	Name string
+	Args []string
+}
+
+//QosHandler is a function which executes a qos command and returns its output
+type QosHandler func(cmd *QosCommand) string
+
+//qosCommands are the commands of dubbo qos and telnet
+var qosCommands = map[string]bool{
+	"cd": true, "clear": true, "count": true, "exit": true, "help": true, "invoke": true, "log": true,
+	"ls": true, "offline": true, "online": true, "ps": true, "pwd": true, "quit": true, "ready": true,
+	"select": true, "shutdown": true, "status": true, "trace": true, "version": true,
+}
+
+var qosHandlers = map[string]QosHandler{}
+
+var qosHandlersMtx sync.RWMutex
+
+//RegisterQosHandler is a function which sets the handler of a qos command, nil removes the handler
+func RegisterQosHandler(name string, handler QosHandler) {
+	qosHandlersMtx.Lock()
+	if handler == nil {
+		delete(qosHandlers, name)
+	} else {
+		qosHandlers[name] = handler
+	}
+	qosHandlersMtx.Unlock()
+}
+
+//qosLine returns the first line of data, ok is false if the line is not terminated yet
+func qosLine(data []byte) (string, bool) {
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return string(data), false
+	}
+	return strings.TrimRight(string(data[:end]), "\r"), true
+}
+
+//IsQosCommand is a function which checks whether data starts with a qos command rather than a dubbo frame
+func IsQosCommand(data []byte) bool {
+	line, _ := qosLine(data)
+	fields := strings.Fields(line)
+	return len(fields) > 0 && qosCommands[fields[0]] && !strings.HasPrefix(line, " ")
+}
+
+//ParseQosCommand is a function which parses the qos command line at the start of data,
+//ok is false if data does not start with a complete qos command line
+func ParseQosCommand(data []byte) (*QosCommand, bool) {
+	line, complete := qosLine(data)
+	if !complete || !IsQosCommand(data) {
+		return nil, false
+	}
+	fields := strings.Fields(line)
+	return &QosCommand{fields[0], fields[1:]}, true
+}
+
+//HandleQosCommand is a function which executes cmd by its registered handler,
+//the output of commands without handler reports them as unsupported like dubbo does
+func HandleQosCommand(cmd *QosCommand) string {
+	qosHandlersMtx.RLock()
+	handler, ok := qosHandlers[cmd.Name]
+	qosHandlersMtx.RUnlock()
+	if !ok {
+		return "Unsupported command: " + cmd.Name
+	}
+	return handler(cmd)
+}
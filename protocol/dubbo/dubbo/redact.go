@@ -0,0 +1,101 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+//Redactor is a function which returns what to render in logs and traces for the argument at argIndex of
+//a call, such as a masked copy of value. It is never consulted for the arguments being forwarded
+type Redactor func(interfaceName, method string, argIndex int, value interface{}) interface{}
+
+var redactor Redactor
+var redactorMtx sync.RWMutex
+
+//SetRedactor is a function which sets the redactor of rendered arguments, nil renders them as they are
+func SetRedactor(r Redactor) {
+	redactorMtx.Lock()
+	redactor = r
+	redactorMtx.Unlock()
+}
+
+//AttachmentRedactor is a function which returns what to render in traces for the attachment key of a call,
+//such as a masked value. It is never consulted for the attachments being forwarded
+type AttachmentRedactor func(interfaceName, method, key, value string) string
+
+var attachmentRedactor AttachmentRedactor
+
+//SetAttachmentRedactor is a function which sets the redactor of rendered attachments, nil renders them as
+//they are
+func SetAttachmentRedactor(r AttachmentRedactor) {
+	redactorMtx.Lock()
+	attachmentRedactor = r
+	redactorMtx.Unlock()
+}
+
+//redactedArguments returns the values of the arguments of req passed through the redactor
+func redactedArguments(req *Request) []interface{} {
+	redactorMtx.RLock()
+	r := redactor
+	redactorMtx.RUnlock()
+
+	interfaceName := req.GetAttachment(PathKey, "")
+	method := req.GetMethodName()
+	values := make([]interface{}, len(req.GetArguments()))
+	for i, arg := range req.GetArguments() {
+		values[i] = arg.GetValue()
+		if r != nil {
+			values[i] = r(interfaceName, method, i, values[i])
+		}
+	}
+	return values
+}
+
+//redactedAttachments returns a copy of the attachments of a call passed through the attachment redactor,
+//attachments itself when there is no redactor
+func redactedAttachments(interfaceName, method string, attachments map[string]string) map[string]string {
+	redactorMtx.RLock()
+	r := attachmentRedactor
+	redactorMtx.RUnlock()
+	if r == nil || attachments == nil {
+		return attachments
+	}
+	redacted := make(map[string]string, len(attachments))
+	for key, value := range attachments {
+		redacted[key] = r(interfaceName, method, key, value)
+	}
+	return redacted
+}
+
+//FormatArguments is a function which renders the call of req as interface.method(arg, ...) for logs and
+//traces, the arguments are passed through the redactor while the ones of req are left untouched
+func FormatArguments(req *Request) string {
+	var out bytes.Buffer
+	out.WriteString(req.GetAttachment(PathKey, "") + "." + req.GetMethodName() + "(")
+	for i, value := range redactedArguments(req) {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		fmt.Fprintf(&out, "%v", value)
+	}
+	out.WriteString(")")
+	return out.String()
+}
@@ -0,0 +1,48 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"testing"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatArguments_Redactor(t *testing.T) {
+	req := newTestRequest()
+	req.SetArguments([]util.Argument{
+		{JavaType: util.JavaString, Value: "tom"},
+		{JavaType: util.JavaString, Value: "4111-1111-1111-1111"},
+	})
+	assert.Equal(t, "com.demo.HelloService.sayHello(tom, 4111-1111-1111-1111)", FormatArguments(req))
+
+	SetRedactor(func(interfaceName, method string, argIndex int, value interface{}) interface{} {
+		if interfaceName == "com.demo.HelloService" && method == "sayHello" && argIndex == 1 {
+			return "***"
+		}
+		return value
+	})
+	defer SetRedactor(nil)
+	assert.Equal(t, "com.demo.HelloService.sayHello(tom, ***)", FormatArguments(req))
+
+	d := &DubboCodec{}
+	forwarded, ret := decodeRequest(d, encodeRequest(t, d, req))
+	assert.Equal(t, 0, ret)
+	assert.Equal(t, "4111-1111-1111-1111", forwarded.GetArguments()[1].GetValue())
+}
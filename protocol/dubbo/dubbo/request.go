@@ -19,7 +19,10 @@ package dubbo
 
 import (
 	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 //GCurMSGID is a variable of type int64
@@ -43,6 +46,14 @@ type Request struct {
 	twoWay   bool
 	isBroken bool
 	data     interface{}
+	//serialization is the serialization id in the header of decoded request
+	serialization byte
+	//category is the category of registry operations, such as providers
+	category string
+	//rawArguments are the encoded arguments of decoded request when the codec captures them
+	rawArguments []byte
+	//omitAttachments writes an empty attachment map for request
+	omitAttachments bool
 }
 
 //NewDubboRequest is a function which creates new dubbo request
@@ -61,6 +72,43 @@ func NewDubboRequest() *Request {
 	return tmp
 }
 
+//NewOneWayRequest is a function which creates a dubbo request expecting no response, e.g. notifications
+func NewOneWayRequest(interfaceName string, method string) *Request {
+	tmp := NewDubboRequest()
+	tmp.SetTwoWay(false)
+	tmp.SetMethodName(method)
+	tmp.SetAttachment(PathKey, interfaceName)
+	return tmp
+}
+
+//NewReadOnlyEvent is a function which creates the event telling the peer not to send new requests
+func NewReadOnlyEvent() *Request {
+	tmp := NewDubboRequest()
+	tmp.SetTwoWay(false)
+	tmp.SetEvent(ReadOnlyEvent)
+	return tmp
+}
+
+//AsShadow is a method which clones request into a one-way request with a new id, which mirrors the request
+//to a shadow backend without waiting for a response. The attachments and arguments are copied, argument
+//values are shared with request
+func (p *Request) AsShadow() *Request {
+	shadow := *p
+	shadow.SetMsgID(GenerateMsgID())
+	shadow.SetTwoWay(false)
+	shadow.arguments = append([]util.Argument(nil), p.arguments...)
+	shadow.attachments = make(map[string]string, len(p.attachments))
+	for k, v := range p.attachments {
+		shadow.attachments[k] = v
+	}
+	return &shadow
+}
+
+//Reset is a method which clears all fields of request so that it can be reused
+func (p *Request) Reset() {
+	*p = Request{}
+}
+
 //IsBroken check whether the connection is broken
 func (p *Request) IsBroken() bool {
 	return p.isBroken
@@ -83,7 +131,9 @@ func (p *Request) GetMsgID() int64 {
 	return p.msgID
 }
 
-//SetMsgID sets message ID
+//SetMsgID sets message ID.
+//Ids must be unique on a connection, requests from several clients sharing one upstream connection
+//should be sent through a Multiplexer instead of being re-stamped by hand
 func (p *Request) SetMsgID(id int64) {
 	p.msgID = id
 }
@@ -93,16 +143,125 @@ func (p *Request) GetStatus() byte {
 	return p.status
 }
 
+//DecodeError returns the error of a request whose body failed to decode, with the status set by the decoder,
+//BadRequest if it set none
+func (p *Request) DecodeError() *CodecError {
+	status := p.status
+	if status == 0 || status == Ok {
+		status = BadRequest
+	}
+	msg, _ := p.data.(string)
+	return &CodecError{status, msg}
+}
+
 //IsHeartbeat is method
 func (p *Request) IsHeartbeat() bool {
 	return p.event && HeartBeatEvent == p.data
 }
 
+//IsReadOnlyEvent is a method which checks whether it is a read-only event
+func (p *Request) IsReadOnlyEvent() bool {
+	return p.event && ReadOnlyEvent == p.data
+}
+
 //IsEvent checks whether event is present
 func (p *Request) IsEvent() bool {
 	return p.event
 }
 
+//GetSerialization is a method which gets the serialization id of request header
+func (p *Request) GetSerialization() byte {
+	return p.serialization
+}
+
+//SetSerialization is a method which sets the serialization id of request header
+func (p *Request) SetSerialization(id byte) {
+	p.serialization = id
+}
+
+//GetRegistryCategory is a method which gets the category of registry operations such as subscribe
+func (p *Request) GetRegistryCategory() string {
+	return p.category
+}
+
+//SetRegistryCategory is a method which sets the category of registry operations
+func (p *Request) SetRegistryCategory(category string) {
+	p.category = category
+}
+
+//GetRawArguments is a method which gets the encoded arguments of request, as captured by a codec
+//with CaptureRaw set, nil if they were not captured
+func (p *Request) GetRawArguments() []byte {
+	return p.rawArguments
+}
+
+//SetRawArguments is a method which sets the encoded arguments of request
+func (p *Request) SetRawArguments(raw []byte) {
+	p.rawArguments = raw
+}
+
+//ServiceKey is a method which returns the key registries know the service as, interface:version:group.
+//Empty parts at the end are omitted and the default version 0.0.0 counts as empty
+func (p *Request) ServiceKey() string {
+	iface := p.GetAttachment(InterfaceKey, p.GetAttachment(PathKey, ""))
+	version := p.GetAttachment(VersionKey, "")
+	if version == "0.0.0" {
+		version = ""
+	}
+	parts := []string{iface, version, p.GetAttachment(GroupKey, "")}
+	for len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ":")
+}
+
+//WantsKeepAlive is a method which reports whether the client prefers its connection to be reused,
+//which is the default unless the keepalive attachment is "false" or "close"
+func (p *Request) WantsKeepAlive() bool {
+	switch strings.ToLower(p.GetAttachment(KeepAliveKey, "")) {
+	case "false", "close":
+		return false
+	}
+	return true
+}
+
+//IsSticky is a method which reports whether the consumer asks for its calls to stick to the provider
+//which served the previous ones, signaled by the sticky attachment being "true"
+func (p *Request) IsSticky() bool {
+	return strings.EqualFold(p.GetAttachment(StickyKey, ""), "true")
+}
+
+//GetLoadBalance is a method which gets the load balance strategy the consumer asks for, such as roundrobin,
+//it is empty if the consumer leaves the strategy to the proxy
+func (p *Request) GetLoadBalance() string {
+	return p.GetAttachment(LoadBalanceKey, "")
+}
+
+//GetValidation is a method which gets the kind of argument validation the consumer enables, such as
+//jvalidation for JSR-303, it is empty if validation is not enabled
+func (p *Request) GetValidation() string {
+	return p.GetAttachment(ValidationKey, "")
+}
+
+//GetValidationGroups is a method which gets the JSR-303 groups the arguments of request are validated in,
+//from the comma separated class names of the validation group attachment
+func (p *Request) GetValidationGroups() []string {
+	groups := p.GetAttachment(ValidationGroupKey, "")
+	if groups == "" {
+		return nil
+	}
+	return strings.Split(groups, CommaSeparator)
+}
+
+//GetExecutor is a method which gets the name of the provider thread pool the request asks for,
+//the executor attachment is preferred over threadpool, it is empty if neither was sent
+func (p *Request) GetExecutor() string {
+	if executor := p.GetAttachment(ExecutorKey, ""); executor != "" {
+		return executor
+	}
+	return p.GetAttachment(ThreadPoolKey, "")
+}
+
 //SetTwoWay is a method which set the connection to two-way
 func (p *Request) SetTwoWay(is bool) {
 	p.twoWay = is
@@ -113,6 +272,22 @@ func (p *Request) IsTwoWay() bool {
 	return p.twoWay
 }
 
+//SetOmitAttachments is a method which makes the codec write an empty attachment map for request, for
+//minimal frames of latency-critical calls. The path and versions are still sent in the body head
+func (p *Request) SetOmitAttachments(omit bool) {
+	p.omitAttachments = omit
+}
+
+//OmitsAttachments is a method which checks whether the attachments of request are left out when encoded
+func (p *Request) OmitsAttachments() bool {
+	return p.omitAttachments
+}
+
+//IsGeneric is a method which checks whether the request is a generic invocation
+func (p *Request) IsGeneric() bool {
+	return p.methodName == GenericInvoke || p.methodName == GenericInvokeAsync
+}
+
 //SetData is a method which sets data
 func (p *Request) SetData(data interface{}) {
 	p.data = data
@@ -178,6 +353,79 @@ func (p *DubboRPCInvocation) SetAttachments(attachs map[string]string) {
 	p.attachments = attachs
 }
 
+//AppendVia is a method which appends the identity of current hop to the via attachment
+func (p *DubboRPCInvocation) AppendVia(hop string) {
+	via := p.GetAttachment(ViaKey, "")
+	if via != "" {
+		via += CommaSeparator
+	}
+	p.SetAttachment(ViaKey, via+hop)
+}
+
+//GetViaChain is a method which returns the hops recorded in the via attachment, in order
+func (p *DubboRPCInvocation) GetViaChain() []string {
+	via := p.GetAttachment(ViaKey, "")
+	if via == "" {
+		return nil
+	}
+	return strings.Split(via, CommaSeparator)
+}
+
+//EnsureTimeout is a method which sets the timeout attachment to def if it is missing or invalid,
+//it returns the timeout the backend will see
+func (p *DubboRPCInvocation) EnsureTimeout(def time.Duration) time.Duration {
+	if ms, err := strconv.ParseInt(p.GetAttachment(TimeoutKey, ""), 10, 64); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	p.SetAttachment(TimeoutKey, strconv.FormatInt(int64(def/time.Millisecond), 10))
+	return def
+}
+
+//GetProtocolUpgradeHint is a method which gets the protocol the client is migrating to, such as "tri"
+//for dubbo 3, it is empty if the client sent no hint
+func (p *DubboRPCInvocation) GetProtocolUpgradeHint() string {
+	return p.GetAttachment(UpgradeKey, "")
+}
+
+//SetProtocolUpgradeHint is a method which sets the protocol the client is migrating to
+func (p *DubboRPCInvocation) SetProtocolUpgradeHint(protocol string) {
+	p.SetAttachment(UpgradeKey, protocol)
+}
+
+//GetSide is a method which gets the side the invocation was sent from, ConsumerSide or ProviderSide,
+//it is empty if the metadata was not sent
+func (p *DubboRPCInvocation) GetSide() string {
+	return p.GetAttachment(SideKey, "")
+}
+
+//GetMethods is a method which gets the methods declared in the metadata of a consumer or a provider
+//registration, blanks around the names and empty names are dropped
+func (p *DubboRPCInvocation) GetMethods() []string {
+	var methods []string
+	for _, method := range strings.Split(p.GetAttachment(MethodsKey, ""), CommaSeparator) {
+		if method = strings.TrimSpace(method); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+//GetConsumerPID is a method which gets the process id of the consumer which sent the invocation,
+//it is 0 if the consumer did not send it
+func (p *DubboRPCInvocation) GetConsumerPID() int {
+	pid, err := strconv.Atoi(p.GetAttachment(PidKey, ""))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+//GetConsumerInterface is a method which gets the interface the consumer invokes as declared in its metadata,
+//the path is used if the interface was not sent
+func (p *DubboRPCInvocation) GetConsumerInterface() string {
+	return p.GetAttachment(InterfaceKey, p.GetAttachment(PathKey, ""))
+}
+
 //GetArguments is a method which gets arguments
 func (p *DubboRPCInvocation) GetArguments() []util.Argument {
 	return p.arguments
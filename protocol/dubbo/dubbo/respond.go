@@ -17,6 +17,10 @@
 
 package dubbo
 
+import (
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
 const (
 	Ok                             = byte(20)
 	ClientTimeout                  = byte(30)
@@ -33,6 +37,10 @@ const (
 	ResponseWithException = byte(0)
 	ResponseValue         = byte(1)
 	ResponseNullValue     = byte(2)
+	//the value types above followed by the attachments of response, sent to peers of version 2.0.2 or later
+	ResponseWithExceptionWithAttachments = byte(3)
+	ResponseValueWithAttachments         = byte(4)
+	ResponseNullValueWithAttachments     = byte(5)
 )
 
 //DubboRsp is a struct which has attributes for dubbo response
@@ -42,7 +50,12 @@ type DubboRsp struct {
 	mVersion  string
 	mStatus   byte
 	mEvent    bool
+	mGeneric  bool
 	mErrorMsg string
+	//serialization is the serialization id of decoded response header
+	serialization byte
+	//rawValue is the encoded value or exception of decoded response when the codec captures it
+	rawValue []byte
 }
 
 //Init method initializes value
@@ -51,10 +64,43 @@ func (p *DubboRsp) Init() {
 	p.mVersion = "0.0.0"
 	p.mStatus = Ok
 	p.mEvent = false
+	p.mGeneric = false
 	p.mErrorMsg = ""
 	//p.mResult = nil
 }
 
+//NewHeartbeatResponseFor is a function which creates the heartbeat response answering req
+func NewHeartbeatResponseFor(req *Request) *DubboRsp {
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(req.GetMsgID())
+	rsp.SetEvent(true)
+	rsp.SetValue(nil)
+	return rsp
+}
+
+//NewErrorResponse is a function which creates the response reporting a failure of request id with status,
+//which should not be Ok, and the error message
+func NewErrorResponse(id int64, status byte, msg string) *DubboRsp {
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetID(id)
+	rsp.SetStatus(status)
+	rsp.SetErrorMsg(msg)
+	return rsp
+}
+
+//GetVersion is a method which gets the dubbo version of the peer the response is sent to
+func (p *DubboRsp) GetVersion() string {
+	return p.mVersion
+}
+
+//SetVersion is a method which sets the dubbo version of the peer the response is sent to,
+//attachments are only encoded for peers which support them
+func (p *DubboRsp) SetVersion(ver string) {
+	p.mVersion = ver
+}
+
 //IsHeartbeat is a method which checks for heartbeat
 func (p *DubboRsp) IsHeartbeat() bool {
 	return p.mEvent
@@ -65,6 +111,41 @@ func (p *DubboRsp) SetEvent(bEvt bool) {
 	p.mEvent = bEvt
 }
 
+//IsGeneric is a method which checks whether it answers a generic invocation
+func (p *DubboRsp) IsGeneric() bool {
+	return p.mGeneric
+}
+
+//SetGeneric is a method which marks the response as the answer of a generic invocation
+func (p *DubboRsp) SetGeneric(generic bool) {
+	p.mGeneric = generic
+}
+
+//GetGenericValue is a method which gets the value in a form accepted by encoding/json
+func (p *DubboRsp) GetGenericValue() interface{} {
+	return util.ToJSONValue(p.GetValue())
+}
+
+//GetDubboException is a method which gets the exception thrown by the service, nil if the call succeeded
+func (p *DubboRsp) GetDubboException() *DubboException {
+	if except := p.GetException(); except != nil {
+		return NewDubboException(except)
+	}
+	if p.mStatus != ServiceError {
+		return nil
+	}
+	return NewDubboException(p.GetValue())
+}
+
+//GetThrowableValue is a method which gets the value as a DubboException if the service returned a java Throwable
+//as its normal value, it is nil for other values and for failed calls
+func (p *DubboRsp) GetThrowableValue() *DubboException {
+	if p.mStatus != Ok || !isThrowable(p.GetValue()) {
+		return nil
+	}
+	return NewDubboException(p.GetValue())
+}
+
 //GetStatus is a method which gets status
 func (p *DubboRsp) GetStatus() byte {
 	return p.mStatus
@@ -75,6 +156,16 @@ func (p *DubboRsp) SetStatus(status byte) {
 	p.mStatus = status
 }
 
+//GetSerialization is a method which gets the serialization id of response header
+func (p *DubboRsp) GetSerialization() byte {
+	return p.serialization
+}
+
+//SetSerialization is a method which sets the serialization id of response header
+func (p *DubboRsp) SetSerialization(id byte) {
+	p.serialization = id
+}
+
 //GetID is a method which gets ID
 func (p *DubboRsp) GetID() int64 {
 	return p.mID
@@ -95,6 +186,27 @@ func (p *DubboRsp) SetErrorMsg(err string) {
 	p.mErrorMsg = err
 }
 
+//GetRawValue is a method which gets the encoded value or exception of response, as captured by a codec
+//with CaptureRaw set, nil if it was not captured
+func (p *DubboRsp) GetRawValue() []byte {
+	return p.rawValue
+}
+
+//SetRawValue is a method which sets the encoded value or exception of response
+func (p *DubboRsp) SetRawValue(raw []byte) {
+	p.rawValue = raw
+}
+
+//ResponseResult is a struct which holds the outcome of a decoded response
+type ResponseResult struct {
+	ID          int64
+	Status      byte
+	Value       interface{}
+	Exception   interface{}
+	ErrorMsg    string
+	Attachments map[string]string
+}
+
 //DubboRPCResult is a struct which has attibutes for dubbo rpc result
 type DubboRPCResult struct {
 	attchments map[string]string
@@ -0,0 +1,200 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//CodecSession is a struct which encodes frames for one connection and tracks the requests waiting for response
+type CodecSession struct {
+	codec    *DubboCodec
+	writer   io.Writer
+	mtx      sync.Mutex
+	inflight map[int64]bool
+	draining bool
+	drained  chan struct{}
+	meter    *FlowMeter
+	checkIDs bool
+	peer     *Handshake
+	serialID byte                //serialization of the responses of peer
+	serial   util.BodySerializer //serializer of serialID, nil until a response of peer is decoded
+}
+
+//NewCodecSession is a function which creates a codec session writing frames to w
+func NewCodecSession(codec *DubboCodec, w io.Writer) *CodecSession {
+	return &CodecSession{
+		codec:    codec,
+		writer:   w,
+		inflight: make(map[int64]bool),
+	}
+}
+
+//Send is a method which encodes the request and writes it, two-way requests are tracked until Done
+func (s *CodecSession) Send(req *Request) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.draining {
+		return ErrSessionDraining
+	}
+	if s.checkIDs && req.IsTwoWay() && s.inflight[req.GetMsgID()] {
+		return ErrDuplicateRequestID
+	}
+	if err := s.write(req); err != nil {
+		return err
+	}
+	if req.IsTwoWay() && !req.IsEvent() {
+		s.inflight[req.GetMsgID()] = true
+	}
+	return nil
+}
+
+func (s *CodecSession) write(req *Request) error {
+	var buffer util.WriteBuffer
+	buffer.Init(s.codec.EstimateSize(req))
+	if s.codec.EncodeDubboReq(req, &buffer) != 0 {
+		return ErrEncodeFailed
+	}
+	n, err := s.writer.Write(buffer.GetValidData())
+	if s.meter != nil {
+		s.meter.Record(n)
+	}
+	return err
+}
+
+//SetFlowMeter is a method which sets the meter counting the frames written and the responses decoded by session
+func (s *CodecSession) SetFlowMeter(m *FlowMeter) {
+	s.mtx.Lock()
+	s.meter = m
+	s.mtx.Unlock()
+}
+
+//SetCheckDuplicateIDs is a method which makes Send reject a two-way request whose id is still in flight,
+//a debugging aid for clients which reuse ids, it is off by default
+func (s *CodecSession) SetCheckDuplicateIDs(check bool) {
+	s.mtx.Lock()
+	s.checkIDs = check
+	s.mtx.Unlock()
+}
+
+//SendHandshake is a method which writes the handshake frame of h, it is only meant for links whose peer is
+//known to be a mesher, the session never sends it on its own
+func (s *CodecSession) SendHandshake(h *Handshake) error {
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	if err := EncodeHandshake(h, &buffer); err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := s.writer.Write(buffer.GetValidData())
+	return err
+}
+
+//SetPeerHandshake is a method which records the handshake received from the peer of session
+func (s *CodecSession) SetPeerHandshake(h *Handshake) {
+	s.mtx.Lock()
+	s.peer = h
+	s.mtx.Unlock()
+}
+
+//PeerHandshake is a method which returns the handshake received from the peer, nil if there was none
+func (s *CodecSession) PeerHandshake() *Handshake {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.peer
+}
+
+//DecodeResponse is a method which decodes a response received on session. The serializer of the last response
+//decoded successfully is remembered and used for the responses declaring the same serialization without looking
+//it up again, while the allowed serializations of codec are checked for every response. A response declaring
+//another serialization, or failing to decode, forgets it
+func (s *CodecSession) DecodeResponse(head []byte, body *util.ReadBuffer) (*ResponseResult, error) {
+	if len(head) < HeaderLength {
+		return nil, ErrInvalidHeader
+	}
+	id := head[2] & SerializationMask
+	s.mtx.Lock()
+	var known util.BodySerializer
+	if s.serialID == id {
+		known = s.serial
+	}
+	if s.meter != nil {
+		s.meter.Record(HeaderLength + len(body.Bytes()))
+	}
+	s.mtx.Unlock()
+
+	result, serializer, err := s.codec.decodeResponse(head, body, known)
+	s.mtx.Lock()
+	s.serialID, s.serial = id, serializer
+	s.mtx.Unlock()
+	return result, err
+}
+
+//PeerSerialization is a method which returns the serialization remembered for the responses of peer
+func (s *CodecSession) PeerSerialization() (byte, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.serialID, s.serial != nil
+}
+
+//Done is a method which marks the request of id as answered
+func (s *CodecSession) Done(id int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.inflight, id)
+	if s.drained != nil && len(s.inflight) == 0 {
+		close(s.drained)
+		s.drained = nil
+	}
+}
+
+//InFlight is a method which returns the number of requests waiting for response
+func (s *CodecSession) InFlight() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.inflight)
+}
+
+//Drain is a method which stops sending, emits a read-only event and waits for the pending responses
+//until ctx is done, it returns the number of requests still in flight
+func (s *CodecSession) Drain(ctx context.Context) (int, error) {
+	s.mtx.Lock()
+	s.draining = true
+	err := s.write(NewReadOnlyEvent())
+	drained := make(chan struct{})
+	if len(s.inflight) == 0 {
+		close(drained)
+	} else {
+		s.drained = drained
+	}
+	s.mtx.Unlock()
+	if err != nil {
+		return s.InFlight(), err
+	}
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+	return s.InFlight(), nil
+}
@@ -0,0 +1,148 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecSession_Drain(t *testing.T) {
+	var out bytes.Buffer
+	s := NewCodecSession(&DubboCodec{}, &out)
+	completed := newTestRequest()
+	stuck := newTestRequest()
+	assert.NoError(t, s.Send(completed))
+	assert.NoError(t, s.Send(stuck))
+	assert.Equal(t, 2, s.InFlight())
+	written := out.Len()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Done(completed.GetMsgID())
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	remaining, err := s.Drain(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+	assert.Equal(t, ErrSessionDraining, s.Send(newTestRequest()))
+
+	event, ret := decodeRequest(&DubboCodec{}, out.Bytes()[written:])
+	assert.Equal(t, 0, ret)
+	assert.True(t, event.IsReadOnlyEvent())
+	assert.False(t, event.IsTwoWay())
+}
+
+func TestCodecSession_DrainIdle(t *testing.T) {
+	var out bytes.Buffer
+	s := NewCodecSession(&DubboCodec{}, &out)
+	req := newTestRequest()
+	assert.NoError(t, s.Send(req))
+	go s.Done(req.GetMsgID())
+
+	remaining, err := s.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, remaining)
+}
+
+func TestCodecSession_DuplicateIDs(t *testing.T) {
+	var out bytes.Buffer
+	s := NewCodecSession(&DubboCodec{}, &out)
+	first := newTestRequest()
+	second := newTestRequest()
+	second.SetMsgID(first.GetMsgID())
+	assert.NoError(t, s.Send(first))
+	assert.NoError(t, s.Send(second))
+	s.Done(first.GetMsgID())
+
+	s.SetCheckDuplicateIDs(true)
+	assert.NoError(t, s.Send(first))
+	written := out.Len()
+	assert.Equal(t, ErrDuplicateRequestID, s.Send(second))
+	assert.Equal(t, written, out.Len())
+	s.Done(first.GetMsgID())
+	assert.NoError(t, s.Send(second))
+}
+
+func decodeSessionResponse(s *CodecSession, frame []byte) (*ResponseResult, error) {
+	var body util.ReadBuffer
+	body.SetBuffer(frame[HeaderLength:])
+	return s.DecodeResponse(frame[:HeaderLength], &body)
+}
+
+func TestCodecSession_PeerSerialization(t *testing.T) {
+	d := &DubboCodec{AllowedSerializations: []byte{Hessian2}}
+	s := NewCodecSession(d, &bytes.Buffer{})
+	rsp := &DubboRsp{}
+	rsp.Init()
+	rsp.SetValue("hello")
+	frame := encodeResponse(t, d, rsp)
+	_, known := s.PeerSerialization()
+	assert.False(t, known)
+
+	result, err := decodeSessionResponse(s, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", result.Value)
+	id, known := s.PeerSerialization()
+	assert.True(t, known)
+	assert.Equal(t, Hessian2, id)
+
+	//the remembered serialization is still checked against the allowed serializations
+	d.AllowedSerializations = []byte{FastJSON}
+	_, err = decodeSessionResponse(s, frame)
+	assert.Equal(t, ErrSerializationNotAllowed, err)
+	_, known = s.PeerSerialization()
+	assert.False(t, known)
+
+	d.AllowedSerializations = []byte{Hessian2}
+	_, err = decodeSessionResponse(s, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, util.Hessian2Serializer{}, s.serial)
+	changed := append([]byte(nil), frame...)
+	changed[2] = changed[2]&^SerializationMask | NativeJava
+	_, err = decodeSessionResponse(s, changed)
+	assert.Equal(t, ErrSerializationNotAllowed, err)
+	_, known = s.PeerSerialization()
+	assert.False(t, known)
+
+	//a second allowed serialization replaces the remembered serializer, which is learned again after it
+	_, err = decodeSessionResponse(s, frame)
+	assert.NoError(t, err)
+	d.AllowedSerializations = []byte{Hessian2, NativeJava}
+	d.JavaSerialization = true
+	result, err = decodeSessionResponse(s, javaFrame(0, Ok, []byte("\x77\x02\x01\x01\x74\x00\x02ok")))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result.Value)
+	id, known = s.PeerSerialization()
+	assert.True(t, known)
+	assert.Equal(t, NativeJava, id)
+	assert.Equal(t, util.JavaSerializer{}, s.serial)
+
+	result, err = decodeSessionResponse(s, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", result.Value)
+	id, _ = s.PeerSerialization()
+	assert.Equal(t, Hessian2, id)
+	assert.Equal(t, util.Hessian2Serializer{}, s.serial)
+}
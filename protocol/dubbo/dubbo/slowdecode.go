@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+)
+
+//SlowDecode is a struct which describes a request frame whose body took too long to decode
+type SlowDecode struct {
+	Interface string
+	Method    string
+	Size      int
+	Elapsed   time.Duration
+}
+
+//checkSlowDecode reports the decode of req started at start if it took longer than the threshold of codec
+func (p *DubboCodec) checkSlowDecode(req *Request, bodyBuf *util.ReadBuffer, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < p.SlowDecodeThreshold {
+		return
+	}
+	slow := SlowDecode{
+		Interface: req.GetAttachment(PathKey, ""),
+		Method:    req.GetMethodName(),
+		Size:      len(bodyBuf.Bytes()),
+		Elapsed:   elapsed,
+	}
+	if p.OnSlowDecode != nil {
+		p.OnSlowDecode(slow)
+		return
+	}
+	lager.Logger.Warn(fmt.Sprintf("slow dubbo decode: %s.%s, %d bytes in %s", slow.Interface, slow.Method, slow.Size, slow.Elapsed))
+}
@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"net/http"
+	"sync"
+)
+
+//statusToHTTP maps the statuses of dubbo responses to the http status codes of transcoded responses
+var statusToHTTP = map[byte]int{
+	Ok:              http.StatusOK,
+	ClientTimeout:   http.StatusGatewayTimeout,
+	ServerTimeout:   http.StatusGatewayTimeout,
+	BadRequest:      http.StatusBadRequest,
+	ServiceNotFound: http.StatusNotFound,
+	ServerError:     http.StatusInternalServerError,
+}
+
+var statusToHTTPMtx sync.RWMutex
+
+//RegisterStatusToHTTP is a function which overrides the http status code a dubbo response status is transcoded to
+func RegisterStatusToHTTP(status byte, code int) {
+	statusToHTTPMtx.Lock()
+	statusToHTTP[status] = code
+	statusToHTTPMtx.Unlock()
+}
+
+//StatusToHTTP is a function which returns the http status code of a dubbo response status,
+//statuses without mapping are transcoded to 500
+func StatusToHTTP(status byte) int {
+	statusToHTTPMtx.RLock()
+	defer statusToHTTPMtx.RUnlock()
+	if code, ok := statusToHTTP[status]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
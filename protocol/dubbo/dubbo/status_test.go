@@ -0,0 +1,43 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusToHTTP(t *testing.T) {
+	for status, code := range map[byte]int{
+		Ok:              http.StatusOK,
+		ClientTimeout:   http.StatusGatewayTimeout,
+		ServerTimeout:   http.StatusGatewayTimeout,
+		ServiceNotFound: http.StatusNotFound,
+		BadRequest:      http.StatusBadRequest,
+		ServerError:     http.StatusInternalServerError,
+		ServiceError:    http.StatusInternalServerError,
+	} {
+		assert.Equal(t, code, StatusToHTTP(status), "status %d", status)
+	}
+
+	RegisterStatusToHTTP(ServerThreadPoolExhaustedError, http.StatusServiceUnavailable)
+	defer RegisterStatusToHTTP(ServerThreadPoolExhaustedError, http.StatusInternalServerError)
+	assert.Equal(t, http.StatusServiceUnavailable, StatusToHTTP(ServerThreadPoolExhaustedError))
+}
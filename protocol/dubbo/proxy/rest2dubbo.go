@@ -59,7 +59,7 @@ func ConvertDubboRspToRestRsp(dubboRsp *dubbo.DubboRsp, w http.ResponseWriter, c
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	} else {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(dubbo.StatusToHTTP(status))
 	}
 	return nil
 }
@@ -67,11 +67,6 @@ func ConvertDubboRspToRestRsp(dubboRsp *dubbo.DubboRsp, w http.ResponseWriter, c
 //ConvertHTTPReqToDubboReq is a function which converts http request in to dubbo request
 func ConvertHTTPReqToDubboReq(restReq *http.Request, ctx *dubbo.InvokeContext, inv *invocation.Invocation) error {
 	req := ctx.Req
-	uri := restReq.URL
-	i := 0
-	var dubboArgs []util.Argument
-	queryAgrs := uri.Query()
-	arg := &util.Argument{}
 
 	svcSchema, methd := schema.GetSchemaMethodBySvcURL(inv.MicroServiceName, "", inv.RouteTags.Version(), inv.RouteTags.AppID(),
 		strings.ToLower(restReq.Method), string(restReq.URL.String()))
@@ -83,34 +78,64 @@ func ConvertHTTPReqToDubboReq(restReq *http.Request, ctx *dubbo.InvokeContext, i
 	req.SetAttachment(dubbo.PathKey, svcSchema.Info["x-java-interface"]) //interfaceSchema.JavaClsName
 	req.SetAttachment(dubbo.VersionKey, "0.0.0")
 	ctx.Method = methd
-	var err error
 
 	//处理参数
-	dubboArgs = make([]util.Argument, len(methd.Paras))
+	dubboArgs, err := convertHTTPArguments(restReq, methd)
+	if err != nil {
+		return err
+	}
+	req.SetArguments(dubboArgs)
+
+	return nil
+}
+
+//jsonIntegerTypes are the java types of the body parameters which are read as json numbers
+var jsonIntegerTypes = map[string]bool{
+	util.JavaShort:   true,
+	util.JavaInteger: true,
+	util.JavaLong:    true,
+}
+
+//convertHTTPArguments converts the parameters of http request into the arguments of method. The integral
+//parameters of the body are read as json numbers, which are converted to their java types
+func convertHTTPArguments(restReq *http.Request, methd *schema.DefMethod) ([]util.Argument, error) {
+	i := 0
+	queryAgrs := restReq.URL.Query()
+	arg := &util.Argument{}
+	var err error
+	dubboArgs := make([]util.Argument, len(methd.Paras))
 
 	for _, v := range methd.Paras {
 		var byteTmp []byte
 		var bytesTmp [][]byte
 		itemType := "string" //默认为string
+		fromBody, jsonNumber := false, false
 		if strings.EqualFold(v.Where, "query") {
 			byteTmp = []byte(queryAgrs.Get(v.Name))
 		} else if restReq.Body != nil {
 			byteTmp, _ = ioutil.ReadAll(restReq.Body)
+			fromBody = true
 		}
 		if byteTmp == nil && v.Required {
-			return &util.BaseError{"Param is null"}
+			return nil, &util.BaseError{"Param is null"}
 		}
 		var realJvmType string
 		bytesTmp, realJvmType = getJVMType(v, arg, bytesTmp, restReq.URL)
 		if bytesTmp == nil {
-			arg.Value, err = util.RestByteToValue(arg.JavaType, byteTmp)
+			jType := arg.JavaType
+			if fromBody && jsonIntegerTypes[jType] {
+				//json numbers are decoded as float64, CoerceJSONArguments converts them below
+				jType = util.JavaObject
+				jsonNumber = true
+			}
+			arg.Value, err = util.RestByteToValue(jType, byteTmp)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			arg.Value, err = util.RestBytesToLstValue(itemType, bytesTmp)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -118,12 +143,14 @@ func ConvertHTTPReqToDubboReq(restReq *http.Request, ctx *dubbo.InvokeContext, i
 			arg.JavaType = realJvmType
 		}
 		dubboArgs[i] = *arg
+		if jsonNumber {
+			if err := util.CoerceJSONArguments(dubboArgs[i:i+1], arg.JavaType); err != nil {
+				return nil, err
+			}
+		}
 		i++
 	}
-
-	req.SetArguments(dubboArgs)
-
-	return nil
+	return dubboArgs, nil
 }
 
 func getJVMType(v schema.MethParam, arg *util.Argument, bytesTmp [][]byte, queryAgrs *url.URL) ([][]byte, string) {
@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubboproxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-mesh/mesher/protocol/dubbo/dubbo"
+	"github.com/go-mesh/mesher/protocol/dubbo/schema"
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+//forwardArguments converts the body of a post to method into arguments, and returns them as the provider
+//decodes them
+func forwardArguments(t *testing.T, methd *schema.DefMethod, body string) ([]util.Argument, error) {
+	restReq := httptest.NewRequest("POST", "/demo/count?name=world", strings.NewReader(body))
+	args, err := convertHTTPArguments(restReq, methd)
+	if err != nil {
+		return nil, err
+	}
+	req := dubbo.NewDubboRequest()
+	req.SetMethodName(methd.OperaID)
+	req.SetAttachment(dubbo.PathKey, "com.demo.CountService")
+	req.SetArguments(args)
+	codec := &dubbo.DubboCodec{}
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, 0, codec.EncodeDubboReq(req, &buffer))
+	frame := buffer.GetValidData()
+	var rb util.ReadBuffer
+	rb.SetBuffer(frame[dubbo.HeaderLength:])
+	decoded, err := codec.DecodeRequest(frame[:dubbo.HeaderLength], &rb)
+	assert.NoError(t, err)
+	return decoded.GetArguments(), nil
+}
+
+func TestConvertHTTPArguments_JSONNumbers(t *testing.T) {
+	for _, c := range []struct {
+		dtype    string
+		body     string
+		javaType string
+		value    interface{}
+	}{
+		{util.SchemaInteger, "42", util.JavaInteger, int32(42)},
+		{util.SchemaInt32, "-7", util.JavaInteger, int32(-7)},
+		{util.SchemaInt64, "4294967296", util.JavaLong, int64(4294967296)},
+		{util.SchemaInt64, "-5", util.JavaLong, int64(-5)},
+	} {
+		methd := &schema.DefMethod{OperaID: "count", Paras: []schema.MethParam{
+			{Name: "name", Dtype: util.SchemaString, Where: "query"},
+			{Name: "count", Dtype: c.dtype, Where: "body"},
+		}}
+		args, err := forwardArguments(t, methd, c.body)
+		if assert.NoError(t, err, c.body) && assert.Len(t, args, 2) {
+			assert.Equal(t, "world", args[0].GetValue())
+			assert.Equal(t, c.javaType, args[1].GetJavaType(), c.body)
+			assert.Equal(t, c.value, args[1].GetValue(), c.body)
+		}
+	}
+}
+
+func TestConvertHTTPArguments_LossyJSONNumber(t *testing.T) {
+	for _, c := range []struct {
+		dtype string
+		body  string
+	}{
+		{util.SchemaInteger, "1.5"},
+		{util.SchemaInt32, "2147483648"},
+		{util.SchemaInt64, "1e19"},
+	} {
+		methd := &schema.DefMethod{OperaID: "count", Paras: []schema.MethParam{
+			{Name: "count", Dtype: c.dtype, Where: "body"},
+		}}
+		_, err := forwardArguments(t, methd, c.body)
+		assert.Equal(t, util.ErrLossyNumber, err, c.body)
+	}
+}
@@ -18,7 +18,11 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+
 	"github.com/go-chassis/go-chassis/core/lager"
 	"github.com/go-mesh/mesher/protocol/dubbo/dubbo"
 	"github.com/go-mesh/mesher/protocol/dubbo/proxy"
@@ -52,17 +56,22 @@ func (this RecvTask) Svc(arg interface{}) interface{} {
 type ProcessTask struct {
 	conn    *DubboConnection
 	req     *dubbo.Request
-	bufBody []byte
+	bufBody *util.ReadBuffer
 }
 
 //Svc is a method
 func (this ProcessTask) Svc(arg interface{}) interface{} {
 	if this.conn != nil {
-		this.conn.ProcessBody(this.req, this.bufBody)
+		this.conn.ProcessBody(this.req, this.bufBody.Bytes())
+		this.conn.releaseBudget(len(this.bufBody.Bytes()))
 	}
+	util.PutReadBuffer(this.bufBody)
 	return nil
 }
 
+//maxQosLineLength bounds the qos command lines read by a connection
+const maxQosLineLength = 4096
+
 //DubboConnection is a struct which has attributes for dubbo connection
 type DubboConnection struct {
 	msgque     *util.MsgQueue
@@ -72,6 +81,10 @@ type DubboConnection struct {
 	mtx        sync.Mutex
 	routineMgr *util.RoutineManager
 	closed     bool
+	budget     *dubbo.SessionByteBudget
+	meter      *dubbo.FlowMeter
+	//errorResponses keeps the encoded responses of the requests rejected before their body is decoded
+	errorResponses *dubbo.ErrorResponseCache
 }
 
 //NewDubboConnetction is a function to create new dubbo connection
@@ -79,6 +92,7 @@ func NewDubboConnetction(conn *net.TCPConn, routineMgr *util.RoutineManager) *Du
 	tmp := new(DubboConnection)
 	tmp.conn = conn
 	tmp.codec = dubbo.DubboCodec{}
+	tmp.errorResponses = dubbo.NewErrorResponseCache(&tmp.codec)
 	tmp.msgque = util.NewMsgQueue()
 	tmp.remoteAddr = conn.RemoteAddr().String()
 	tmp.closed = false
@@ -94,6 +108,73 @@ func (this *DubboConnection) Open() {
 	this.routineMgr.Spawn(RecvTask{}, this, fmt.Sprintf("Recv-%s->%s", this.conn.LocalAddr().String(), this.conn.RemoteAddr().String()))
 }
 
+//SetByteBudget is a method which bounds the bytes of the bodies read and not yet decoded, nil means no bound
+func (this *DubboConnection) SetByteBudget(budget *dubbo.SessionByteBudget) {
+	this.budget = budget
+}
+
+//SetFlowMeter is a method which sets the meter counting the frames received and sent on connection
+func (this *DubboConnection) SetFlowMeter(meter *dubbo.FlowMeter) {
+	this.meter = meter
+}
+
+func (this *DubboConnection) record(size int) {
+	if this.meter != nil {
+		this.meter.Record(size)
+	}
+}
+
+func (this *DubboConnection) acquireBudget(bodyLen int) error {
+	if this.budget == nil {
+		return nil
+	}
+	return this.budget.Acquire(bodyLen)
+}
+
+func (this *DubboConnection) releaseBudget(bodyLen int) {
+	if this.budget != nil {
+		this.budget.Release(bodyLen)
+	}
+}
+
+//serializationRejection returns the error answering a request whose header is rejected for its serialization,
+//nil for other results of the header decoder
+func serializationRejection(ret int) *dubbo.CodecError {
+	switch ret {
+	case dubbo.SerializationNotAllowed:
+		return dubbo.ErrSerializationNotAllowed
+	case dubbo.InvalidSerialization:
+		return dubbo.ErrUnknownSerialization
+	}
+	return nil
+}
+
+//headerFailure returns why the connection is closed for the return code of the header decoder, or the empty
+//string when the connection can go on
+func headerFailure(ret int) string {
+	switch ret {
+	case dubbo.UnsupportedProtocol:
+		return "got http/2 preface, triple protocol is not supported"
+	case dubbo.InvalidBodyLength:
+		//the body can not be skipped, the next frame would be read from its middle
+		return "got a negative body length"
+	}
+	return ""
+}
+
+//rejectBody skips the body of a request rejected before its body is decoded and answers it with err
+func (this *DubboConnection) rejectBody(req *dubbo.Request, bodyLen int, err *dubbo.CodecError) error {
+	lager.Logger.Error("Dubbo server reject request: " + err.Error())
+	if _, err := io.CopyN(ioutil.Discard, this.conn, int64(bodyLen)); err != nil {
+		return err
+	}
+	this.record(dubbo.HeaderLength + bodyLen)
+	if req.IsTwoWay() {
+		this.msgque.Enqueue(this.errorResponses.Response(req.GetMsgID(), err))
+	}
+	return nil
+}
+
 //Close is a function to close a connection
 func (this *DubboConnection) Close() {
 	this.mtx.Lock()
@@ -122,6 +203,13 @@ func (this *DubboConnection) MsgRecvLoop() {
 			break
 		}
 
+		if dubbo.IsQosCommand(buf[:size]) {
+			if err := this.recvQos(buf[:size]); err != nil {
+				lager.Logger.Error("Dubbo server Recv qos command: " + err.Error())
+				break
+			}
+			continue
+		}
 		if size < dubbo.HeaderLength {
 			lager.Logger.Info("Invalid msg head")
 			continue
@@ -129,50 +217,85 @@ func (this *DubboConnection) MsgRecvLoop() {
 		req := new(dubbo.Request)
 		bodyLen := 0
 		ret := this.codec.DecodeDubboReqHead(req, buf, &bodyLen)
-		if ret != dubbo.Success {
-			lager.Logger.Info("Invalid msg head")
-			continue
+		if reason := headerFailure(ret); reason != "" {
+			lager.Logger.Error("Dubbo server " + reason)
+			break
 		}
-		body := make([]byte, bodyLen)
-		count := 0
-		for {
-			redBuff := body[count:]
-			size, err = this.conn.Read(redBuff)
-
-			if err != nil {
-				//通知关闭连接
+		rejection := serializationRejection(ret)
+		if ret == dubbo.Success && this.acquireBudget(bodyLen) != nil {
+			rejection = dubbo.ErrByteBudgetExceeded
+		}
+		if rejection != nil {
+			if err := this.rejectBody(req, bodyLen, rejection); err != nil {
 				lager.Logger.Error("Recv: " + err.Error())
-				goto exitloop
-			}
-			count += size
-			if count == bodyLen {
 				break
 			}
+			continue
+		}
+		if ret != dubbo.Success {
+			lager.Logger.Info("Invalid msg head")
+			continue
+		}
+		bodyBuf, err := this.readBody(bodyLen)
+		if err != nil {
+			//通知关闭连接
+			lager.Logger.Error("Recv: " + err.Error())
+			break
 		}
-		this.routineMgr.Spawn(ProcessTask{this, req, body}, nil, fmt.Sprintf("ProcessTask-%d", req.GetMsgID()))
+		this.record(dubbo.HeaderLength + bodyLen)
+		this.routineMgr.Spawn(ProcessTask{this, req, bodyBuf}, nil, fmt.Sprintf("ProcessTask-%d", req.GetMsgID()))
 	}
-exitloop:
 	this.Close()
 }
 
-//ProcessBody is a method to process the body of response
+//readBody reads a body of bodyLen bytes, whose bytes are acquired from the byte budget. The buffer and the
+//bytes are given back when the read fails
+func (this *DubboConnection) readBody(bodyLen int) (*util.ReadBuffer, error) {
+	bodyBuf := util.GetReadBuffer(bodyLen)
+	body := bodyBuf.Bytes()
+	count := 0
+	for {
+		size, err := this.conn.Read(body[count:])
+		if err != nil {
+			util.PutReadBuffer(bodyBuf)
+			this.releaseBudget(bodyLen)
+			return nil, err
+		}
+		count += size
+		if count == bodyLen {
+			return bodyBuf, nil
+		}
+	}
+}
+
+//ProcessBody is a method to process the body of request, requests whose body fails to decode are answered
+//with an error response instead of being handled
 func (this *DubboConnection) ProcessBody(req *dubbo.Request, bufBody []byte) {
 	var buffer util.ReadBuffer
 	buffer.SetBuffer(bufBody)
-	this.codec.DecodeDubboReqBody(req, &buffer)
+	if this.codec.DecodeDubboReqBody(req, &buffer) != 0 || req.IsBroken() {
+		err := req.DecodeError()
+		lager.Logger.Error("Dubbo server reject request: " + err.Error())
+		if req.IsTwoWay() {
+			this.msgque.Enqueue(dubbo.NewErrorResponse(req.GetMsgID(), err.Status, err.Message))
+		}
+		return
+	}
 	this.HandleMsg(req)
 }
 
 //HandleMsg is a method
 func (this *DubboConnection) HandleMsg(req *dubbo.Request) {
+	if req.IsEvent() && !req.IsHeartbeat() {
+		//events such as read-only need no response
+		return
+	}
 	//这里发送Rest请求以及收发送应答
 	ctx := &dubbo.InvokeContext{req, &dubbo.DubboRsp{}, nil, "", this.remoteAddr}
 	ctx.Rsp.Init()
 	ctx.Rsp.SetID(req.GetMsgID())
 	if req.IsHeartbeat() {
-		ctx.Rsp.SetValue(nil)
-		ctx.Rsp.SetEvent(true)
-		ctx.Rsp.SetStatus(dubbo.Ok)
+		ctx.Rsp = dubbo.NewHeartbeatResponseFor(req)
 	} else {
 		//这里重新分配MSGID
 		srcMsgID := ctx.Req.GetMsgID()
@@ -188,12 +311,51 @@ func (this *DubboConnection) HandleMsg(req *dubbo.Request) {
 		}
 		ctx.Req.SetMsgID(srcMsgID)
 		ctx.Rsp.SetID(srcMsgID)
+		ctx.Rsp.SetVersion(req.GetAttachment(dubbo.DubboVersionKey, dubbo.DubboVersion))
 	}
 	if req.IsTwoWay() {
 		this.msgque.Enqueue(ctx.Rsp)
 	}
 }
 
+//recvQos reads the qos command line starting with data and handles the command
+func (this *DubboConnection) recvQos(data []byte) error {
+	line, err := this.readQosLine(data)
+	if err != nil {
+		return err
+	}
+	if cmd, ok := dubbo.ParseQosCommand(line); ok {
+		this.HandleQos(cmd)
+	}
+	return nil
+}
+
+//readQosLine reads the rest of the qos command line starting with data up to its line feed, one byte at a time
+//so that nothing past the line is consumed. Bytes of data past the line feed are dropped, clients send the
+//next command once the prompt of the previous one is written
+func (this *DubboConnection) readQosLine(data []byte) ([]byte, error) {
+	line := append([]byte(nil), data...)
+	next := make([]byte, 1)
+	for bytes.IndexByte(line, '\n') < 0 {
+		if len(line) >= maxQosLineLength {
+			return nil, &util.BaseError{ErrMsg: "qos command line is too long"}
+		}
+		if _, err := io.ReadFull(this.conn, next); err != nil {
+			return nil, err
+		}
+		line = append(line, next[0])
+	}
+	return line, nil
+}
+
+//HandleQos is a method which queues the output of a qos command followed by the prompt, so that it is written
+//by the send loop between responses
+func (this *DubboConnection) HandleQos(cmd *dubbo.QosCommand) {
+	lager.Logger.Info("Dubbo server got qos command " + cmd.Name)
+	out := dubbo.HandleQosCommand(cmd) + "\r\n" + dubbo.QosPrompt
+	this.msgque.Enqueue([]byte(out))
+}
+
 //MsgSndLoop is a method to send data
 func (this *DubboConnection) MsgSndLoop() {
 	for {
@@ -202,10 +364,15 @@ func (this *DubboConnection) MsgSndLoop() {
 			lager.Logger.Error("MsgSndLoop Dequeue: " + err.Error())
 			break
 		}
-		var buffer util.WriteBuffer
-		buffer.Init(0)
-		this.codec.EncodeDubboRsp(msg.(*dubbo.DubboRsp), &buffer)
-		_, err = this.conn.Write(buffer.GetValidData())
+		frame, ok := msg.([]byte) //responses encoded in advance and qos output
+		if !ok {
+			var buffer util.WriteBuffer
+			buffer.Init(0)
+			this.codec.EncodeDubboRsp(msg.(*dubbo.DubboRsp), &buffer)
+			frame = buffer.GetValidData()
+		}
+		size, err := this.conn.Write(frame)
+		this.record(size)
 		if err != nil {
 			lager.Logger.Error("Send exception: " + err.Error())
 			break
@@ -0,0 +1,241 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-mesh/mesher/protocol/dubbo/dubbo"
+	"github.com/go-mesh/mesher/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+//newTestConnection returns an opened connection serving the server end of a loopback connection, and the
+//client end
+func newTestConnection(t *testing.T) (*DubboConnection, net.Conn) {
+	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer l.Close()
+	client, err := net.Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	conn, err := l.AcceptTCP()
+	assert.NoError(t, err)
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	return NewDubboConnetction(conn, nil), client
+}
+
+//readUntil reads from conn until the data read ends with suffix
+func readUntil(t *testing.T, conn net.Conn, suffix string) string {
+	var data []byte
+	buf := make([]byte, 256)
+	for !bytes.HasSuffix(data, []byte(suffix)) {
+		n, err := conn.Read(buf)
+		if !assert.NoError(t, err) {
+			break
+		}
+		data = append(data, buf[:n]...)
+	}
+	return string(data)
+}
+
+func TestDubboConnection_Qos(t *testing.T) {
+	dubbo.RegisterQosHandler("ls", func(cmd *dubbo.QosCommand) string {
+		return "ls " + cmd.Args[len(cmd.Args)-1]
+	})
+	defer dubbo.RegisterQosHandler("ls", nil)
+	dc, client := newTestConnection(t)
+	defer client.Close()
+	dc.Open()
+	defer dc.Close()
+
+	//the line is longer than a frame header and arrives in several writes
+	client.Write([]byte("ls -l com.demo."))
+	time.Sleep(10 * time.Millisecond)
+	client.Write([]byte("HelloService\r\n"))
+	assert.Equal(t, "ls com.demo.HelloService\r\n"+dubbo.QosPrompt, readUntil(t, client, dubbo.QosPrompt))
+
+	client.Write([]byte("pwd\r\n"))
+	assert.Equal(t, "Unsupported command: pwd\r\n"+dubbo.QosPrompt, readUntil(t, client, dubbo.QosPrompt))
+}
+
+//writeRequest writes the frame of req to conn and returns it
+func writeRequest(t *testing.T, conn net.Conn, req *dubbo.Request) []byte {
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, 0, (&dubbo.DubboCodec{}).EncodeDubboReq(req, &buffer))
+	frame := buffer.GetValidData()
+	_, err := conn.Write(frame)
+	assert.NoError(t, err)
+	return frame
+}
+
+//readResponse reads and decodes the next response from conn
+func readResponse(t *testing.T, conn net.Conn) *dubbo.ResponseResult {
+	frame, err := dubbo.NewFrameReader(conn).ReadFrame()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var body util.ReadBuffer
+	body.SetBuffer(frame.Body)
+	rsp, err := (&dubbo.DubboCodec{}).DecodeResponse(frame.Header, &body)
+	assert.NoError(t, err)
+	return rsp
+}
+
+func newHeartbeat() *dubbo.Request {
+	req := dubbo.NewDubboRequest()
+	req.SetEvent(dubbo.HeartBeatEvent)
+	return req
+}
+
+func TestDubboConnection_ByteBudget(t *testing.T) {
+	dc, client := newTestConnection(t)
+	defer client.Close()
+	budget := dubbo.NewSessionByteBudget(1, dubbo.BudgetReject)
+	dc.SetByteBudget(budget)
+	dc.Open()
+	defer dc.Close()
+
+	req := dubbo.NewDubboRequest()
+	req.SetMethodName("sayHello")
+	req.SetAttachment(dubbo.PathKey, "com.demo.HelloService")
+	req.SetArguments([]util.Argument{{JavaType: util.JavaString, Value: "world"}})
+	writeRequest(t, client, req)
+	rsp := readResponse(t, client)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, req.GetMsgID(), rsp.ID)
+		assert.Equal(t, dubbo.ErrByteBudgetExceeded.Status, rsp.Status)
+		assert.Equal(t, dubbo.ErrByteBudgetExceeded.Message, rsp.ErrorMsg)
+	}
+	assert.Equal(t, 0, budget.Used())
+
+	//the one byte body of a heartbeat fits
+	heartbeat := newHeartbeat()
+	writeRequest(t, client, heartbeat)
+	rsp = readResponse(t, client)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, heartbeat.GetMsgID(), rsp.ID)
+		assert.Equal(t, dubbo.Ok, rsp.Status)
+	}
+}
+
+func TestDubboConnection_BodyReadFailure(t *testing.T) {
+	dc, client := newTestConnection(t)
+	budget := dubbo.NewSessionByteBudget(1024, dubbo.BudgetReject)
+	dc.SetByteBudget(budget)
+	done := make(chan struct{})
+	go func() {
+		dc.MsgRecvLoop()
+		close(done)
+	}()
+
+	//the connection is closed in the middle of a body
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	(&dubbo.DubboCodec{}).EncodeDubboReq(newHeartbeat(), &buffer)
+	frame := buffer.GetValidData()
+	util.Int2bytes(100, frame, 12)
+	client.Write(append(frame, make([]byte, 10)...))
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the receive loop should stop when the connection is closed")
+	}
+	assert.Equal(t, 0, budget.Used())
+}
+
+func TestDubboConnection_NegativeBodyLength(t *testing.T) {
+	dc, client := newTestConnection(t)
+	defer client.Close()
+	budget := dubbo.NewSessionByteBudget(1024, dubbo.BudgetReject)
+	dc.SetByteBudget(budget)
+	done := make(chan struct{})
+	go func() {
+		dc.MsgRecvLoop()
+		close(done)
+	}()
+
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	(&dubbo.DubboCodec{}).EncodeDubboReq(newHeartbeat(), &buffer)
+	frame := buffer.GetValidData()
+	util.Int2bytes(-16, frame, 12)
+	client.Write(frame)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the receive loop should stop on a negative body length")
+	}
+	assert.Equal(t, 0, budget.Used())
+}
+
+func TestDubboConnection_SerializationNotAllowed(t *testing.T) {
+	dc, client := newTestConnection(t)
+	defer client.Close()
+	dc.codec.AllowedSerializations = []byte{dubbo.Hessian2 + 1}
+	dc.Open()
+	defer dc.Close()
+
+	for i := 0; i < 2; i++ {
+		heartbeat := newHeartbeat()
+		writeRequest(t, client, heartbeat)
+		rsp := readResponse(t, client)
+		if assert.NotNil(t, rsp) {
+			assert.Equal(t, heartbeat.GetMsgID(), rsp.ID)
+			assert.Equal(t, dubbo.ErrSerializationNotAllowed.Status, rsp.Status)
+			assert.Equal(t, dubbo.ErrSerializationNotAllowed.Message, rsp.ErrorMsg)
+		}
+	}
+}
+
+func TestDubboConnection_UnknownSerialization(t *testing.T) {
+	dc, client := newTestConnection(t)
+	defer client.Close()
+	dc.Open()
+	defer dc.Close()
+
+	heartbeat := newHeartbeat()
+	var buffer util.WriteBuffer
+	buffer.Init(0)
+	assert.Equal(t, 0, (&dubbo.DubboCodec{}).EncodeDubboReq(heartbeat, &buffer))
+	frame := buffer.GetValidData()
+	frame[2] = frame[2]&^dubbo.SerializationMask | dubbo.FastJSON
+	_, err := client.Write(frame)
+	assert.NoError(t, err)
+	rsp := readResponse(t, client)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, heartbeat.GetMsgID(), rsp.ID)
+		assert.Equal(t, dubbo.ErrUnknownSerialization.Status, rsp.Status)
+		assert.Equal(t, dubbo.ErrUnknownSerialization.Message, rsp.ErrorMsg)
+	}
+
+	//the body was skipped, the next frame is read from its start
+	heartbeat = newHeartbeat()
+	writeRequest(t, client, heartbeat)
+	rsp = readResponse(t, client)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, heartbeat.GetMsgID(), rsp.ID)
+		assert.Equal(t, dubbo.Ok, rsp.Status)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	//ErrInvalidTarget is returned when the decode target is not a non-nil pointer
+	ErrInvalidTarget = &BaseError{"hessian decode target must be a non-nil pointer"}
+	//ErrTypeMismatch is returned when a decoded value can not be stored in the target
+	ErrTypeMismatch = &BaseError{"hessian value does not match decode target"}
+)
+
+//assignValue stores the decoded hessian value src into dst
+func assignValue(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	sv, ok := src.(reflect.Value)
+	if !ok {
+		sv = reflect.ValueOf(src)
+	}
+	if sv.Kind() == reflect.Ptr && dst.Kind() != reflect.Ptr && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+	switch {
+	case sv.Type().AssignableTo(dst.Type()):
+		dst.Set(sv)
+	case dst.Kind() == reflect.Slice && sv.Kind() == reflect.Slice:
+		return assignSlice(dst, sv)
+	case dst.Kind() == reflect.Struct && sv.Kind() == reflect.Map:
+		return assignStruct(dst, sv)
+	case dst.Kind() == reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignValue(elem.Elem(), sv.Interface()); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case isNumber(dst.Kind()) && isNumber(sv.Kind()):
+		dst.Set(sv.Convert(dst.Type()))
+	default:
+		return ErrTypeMismatch
+	}
+	return nil
+}
+
+//assignSlice stores every element of the decoded list sv into the slice dst
+func assignSlice(dst, sv reflect.Value) error {
+	out := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+	for i := 0; i < sv.Len(); i++ {
+		if err := assignValue(out.Index(i), sv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	dst.Set(out)
+	return nil
+}
+
+//assignStruct stores the fields of the decoded object sv into the exported fields of dst
+func assignStruct(dst, sv reflect.Value) error {
+	for _, key := range sv.MapKeys() {
+		name := fmt.Sprint(key.Interface())
+		field := dst.FieldByNameFunc(func(f string) bool { return strings.EqualFold(f, name) })
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		if err := assignValue(field, sv.MapIndex(key).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//isNumber reports whether values of kind k are integers or floats
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
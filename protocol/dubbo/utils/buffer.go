@@ -18,7 +18,10 @@
 package util
 
 import (
+	"encoding/binary"
 	"github.com/go-chassis/gohessian"
+	"math"
+	"net"
 	"reflect"
 
 	"fmt"
@@ -33,7 +36,8 @@ func (p *BaseError) Error() string {
 	return p.ErrMsg
 }
 
-//TypMap is a variable of type map
+//TypMap is a variable of type map, which holds the go structs registered for java classes. It is replaced
+//rather than modified by RegisterJavaType and must not be written to
 var TypMap map[string]reflect.Type
 
 func init() {
@@ -51,6 +55,12 @@ type ReadBuffer struct {
 	rdInd    int
 	length   int
 	capacity int
+	maxDepth int
+	maxChars int
+	interner *StringInterner
+	drained  bool        //the last byte was read, rdInd is kept on it
+	overread int         //bytes read again after the buffer was drained
+	java     *javaStream //the java serialization stream the values of the body are read from
 }
 
 //WriteBuffer is a struct
@@ -70,6 +80,13 @@ func (b *WriteBuffer) Init(size int) {
 	b.capacity = size
 }
 
+//Grow is a method which makes room for n more bytes, so they are written without reallocation
+func (b *WriteBuffer) Grow(n int) {
+	if b.wrInd+n > b.capacity {
+		b.grow(b.wrInd + n - b.capacity)
+	}
+}
+
 //Write is a method to write into buffer
 func (b *WriteBuffer) Write(p []byte) (n int, err error) {
 	result := b.WriteBytes(p)
@@ -127,11 +144,49 @@ func (b *WriteBuffer) WriteByte(src byte) error {
 
 //WriteObject is a method to write object
 func (b *WriteBuffer) WriteObject(src interface{}) error {
-	gh := hessian.NewGoHessian(nil, nil)
-	err := gh.ToBytes2(src, b)
+	switch val := src.(type) {
+	case net.IP:
+		return b.writeInetAddress(val)
+	case []JavaEnum:
+		return b.writeEnumSet(val)
+	case map[JavaEnum]interface{}:
+		return b.writeEnumMap(val)
+	case BitSet:
+		return b.writeBitSet(val)
+	case moneyValue:
+		return b.writeMoney(val)
+	case OrderedSet:
+		return b.writeOrderedSet(val)
+	case doubleAdderHandle:
+		return b.writeDoubleAdder(val)
+	case instantHandle:
+		return b.writeInstant(val)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return b.writeDouble(val)
+		}
+	case int64:
+		return b.writeLong(val)
+	}
+	start := b.wrInd
+	gh := hessian.NewGoHessian(nil, newJavaClassNames())
+	err := gh.ToBytes2(toHessianValue(withoutClassKeys(src)), b)
+	if _, javaFields := registeredFieldNames(); err == nil && len(javaFields) > 0 {
+		err = b.renameWritten(start, javaFields)
+	}
 	return err
 }
 
+//writeDouble writes v in the full 8 bytes form. gohessian tries the compact forms for doubles which
+//convert to int64, and the conversion of NaN and infinities is implementation specific
+func (b *WriteBuffer) writeDouble(v float64) error {
+	buf := make([]byte, 9)
+	buf[0] = hessian.BC_DOUBLE
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+	b.WriteBytes(buf)
+	return nil
+}
+
 //WrittenBytes is a methodto get amount of bytes written
 func (b *WriteBuffer) WrittenBytes() int {
 	return b.wrInd
@@ -151,25 +206,69 @@ func (b *WriteBuffer) GetValidData() []byte {
 func (b *ReadBuffer) SetBuffer(src []byte) {
 	b.buffer = src
 	b.rdInd = 0
+	b.drained = false
+	b.overread = 0
+	b.java = nil
 	b.capacity = len(src)
 	b.length = len(src)
 }
 
+//Bytes is a method which returns the valid data of buffer, it can be filled in place
+func (b *ReadBuffer) Bytes() []byte {
+	return b.buffer[:b.length]
+}
+
 //Init is a method to initialize read buffer
 func (b *ReadBuffer) Init(capacity int) {
 	b.buffer = make([]byte, capacity)
 	b.length = 0
 	b.rdInd = 0
+	b.drained = false
+	b.overread = 0
+	b.java = nil
 	b.capacity = capacity
 }
 
-//ReadByte is a method to read particular byte from buffer
+//ReadByte is a method to read particular byte from buffer, it panics if the buffer does not hold an int
 func (b *ReadBuffer) ReadByte() byte {
 	var tmp interface{}
 	tmp, _ = b.ReadObject()
 	return byte(tmp.(int32))
 }
 
+//ErrNotByte is returned when the value read as a byte is not an int
+var ErrNotByte = &BaseError{"hessian value is not a byte"}
+
+//ReadByteE is a method to read particular byte from buffer, it returns ErrTruncatedValue if the buffer is exhausted
+func (b *ReadBuffer) ReadByteE() (byte, error) {
+	if b.exhausted() {
+		return 0, ErrTruncatedValue
+	}
+	tmp, err := b.ReadObject()
+	if err != nil {
+		return 0, err
+	}
+	v, ok := tmp.(int32)
+	if !ok {
+		return 0, ErrNotByte
+	}
+	return byte(v), nil
+}
+
+//exhausted reports whether all bytes of buffer have been read
+func (b *ReadBuffer) exhausted() bool {
+	return b.rdInd >= b.length || b.drained
+}
+
+//Consumed is a method which returns the number of bytes read from buffer, including the bytes
+//read again by readers which went on after the buffer was drained
+func (b *ReadBuffer) Consumed() int {
+	if b.drained {
+		return b.length + b.overread
+	}
+	return b.rdInd
+}
+
 //ReadBytes is a method to read data from buffer
 func (b *ReadBuffer) ReadBytes(len int) []byte {
 	start := b.rdInd
@@ -177,34 +276,199 @@ func (b *ReadBuffer) ReadBytes(len int) []byte {
 	return b.buffer[start:b.rdInd]
 }
 
+//SetMaxDepth is a method to limit how deep the objects read from buffer may nest, 0 means no limit
+func (b *ReadBuffer) SetMaxDepth(depth int) {
+	b.maxDepth = depth
+}
+
+//SetMaxStringLength is a method to limit the length in characters of the strings read by ReadString
+//and ReadStringE, 0 means no limit
+func (b *ReadBuffer) SetMaxStringLength(length int) {
+	b.maxChars = length
+}
+
+//SetInterner is a method to share the backing of the strings read from buffer through interner, nil disables it
+func (b *ReadBuffer) SetInterner(interner *StringInterner) {
+	b.interner = interner
+}
+
+//intern returns the interned copy of obj if it is a string
+func (b *ReadBuffer) intern(obj interface{}) interface{} {
+	if s, ok := obj.(string); ok && b.interner != nil {
+		return b.interner.Intern(s)
+	}
+	return obj
+}
+
+//checkLimits scans the next object and checks it against the limits of buffer
+func (b *ReadBuffer) checkLimits() error {
+	if b.maxDepth <= 0 {
+		return nil
+	}
+	return newScanner(b.buffer[b.rdInd:b.length], b.maxDepth).scanValue(0)
+}
+
+//CheckNextSize is a method which returns ErrValueTooLarge if the next object is encoded in more than max bytes.
+//The object is scanned without being materialized, and the scan stops at the limit
+func (b *ReadBuffer) CheckNextSize(max int) error {
+	s := newScanner(b.buffer[b.rdInd:b.length], 0)
+	s.maxSize = max
+	return s.scanValue(0)
+}
+
 //ReadObject is a method to read buffer and return object
 func (b *ReadBuffer) ReadObject() (interface{}, error) {
-	gh := hessian.NewGoHessian(TypMap, nil)
-	obj, err := gh.ToObject2(b)
-	return obj, err
+	if err := b.checkLimits(); err != nil {
+		return nil, err
+	}
+	if b.isEnumMap() {
+		return b.readEnumMap()
+	}
+	if b.isBitSet() {
+		return b.readBitSet()
+	}
+	if class, ok := b.orderedSetClass(); ok {
+		return b.readOrderedSet(class)
+	}
+	if b.rdInd < b.length && (isTypedList(b.buffer[b.rdInd]) || b.isImmutableMap()) {
+		return b.readTypedList()
+	}
+	return b.readHessian()
 }
 
-//ReadString is a method to read buffer and return as string
+//ReadObjectInto is a method to read buffer and store the object in the value ptr points to
+func (b *ReadBuffer) ReadObjectInto(ptr interface{}) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrInvalidTarget
+	}
+	obj, err := b.ReadObject()
+	if err != nil {
+		return err
+	}
+	return assignValue(rv.Elem(), obj)
+}
+
+//ReadString is a method to read buffer and return as string, a string longer than the max string length
+//is not read and returned as empty, ReadStringE reports it as an error
 func (b *ReadBuffer) ReadString() string {
+	if b.maxChars > 0 && b.checkStringLength() != nil {
+		return ""
+	}
 	gh := hessian.NewGoHessian(nil, nil)
 	obj, _ := gh.ToObject2(b)
-	return obj.(string)
+	return b.intern(obj).(string)
 }
 
-//ReadMap is a method to read buffer and return as a map
-func (b *ReadBuffer) ReadMap() (map[string]string, error) {
+//ErrNotString is returned when the value read as a string is neither a string nor null
+var ErrNotString = &BaseError{"hessian value is not a string"}
+
+//ErrStringTooLong is returned when a string is longer than the max string length of buffer
+var ErrStringTooLong = &BaseError{"hessian string exceeds max length"}
+
+//ReadStringE is a method to read buffer and return as string, null is read as an empty string.
+//The length claimed by the string is checked against the max string length before it is read
+func (b *ReadBuffer) ReadStringE() (string, error) {
+	if b.maxChars > 0 {
+		if err := b.checkStringLength(); err != nil {
+			return "", err
+		}
+	}
+	if b.exhausted() {
+		return "", ErrTruncatedValue
+	}
 	gh := hessian.NewGoHessian(nil, nil)
 	obj, err := gh.ToObject2(b)
 	if err != nil {
+		return "", err
+	}
+	switch val := b.intern(obj).(type) {
+	case string:
+		return val, nil
+	case nil:
+		return "", nil
+	}
+	return "", ErrNotString
+}
+
+//isStringTag reports whether tag starts a string or a string chunk
+func isStringTag(tag byte) bool {
+	return tag <= hessian.STRING_DIRECT_MAX || (tag >= 0x30 && tag <= 0x33) ||
+		tag == hessian.BC_STRING || tag == hessian.BC_STRING_CHUNK
+}
+
+//checkStringLength adds up the lengths claimed by the chunks of the next string, and stops as soon as the sum
+//exceeds the max string length, so the check does not depend on the bytes of string being there
+func (b *ReadBuffer) checkStringLength() error {
+	s := newScanner(b.buffer[b.rdInd:b.length], 0)
+	tag, err := s.next()
+	if err != nil || !isStringTag(tag) {
+		return nil
+	}
+	total := 0
+	for {
+		n, err := s.chunkLength(tag)
+		if err != nil {
+			return err
+		}
+		if total += n; total > b.maxChars {
+			return ErrStringTooLong
+		}
+		if tag != hessian.BC_STRING_CHUNK || s.skip(n) != nil {
+			return nil
+		}
+		if tag, err = s.next(); err != nil {
+			return nil
+		}
+	}
+}
+
+//ErrNotMap is returned when the value read as a map is neither a map nor an object
+var ErrNotMap = &BaseError{"hessian value is not a map or an object"}
+
+//isMapForm reports whether the hessian value starting with tag decodes into a map, attachments are written
+//as an untyped map, as a typed map such as java.util.HashMap or, by some dubbo 2.7 releases, as an object
+func isMapForm(tag byte) bool {
+	return tag == 'H' || tag == 'M' || tag == 'C' || tag == 'O' || (tag >= 0x60 && tag <= 0x6f)
+}
+
+//ReadMap is a method to read buffer and return as a map, values which are not strings are formatted
+func (b *ReadBuffer) ReadMap() (map[string]string, error) {
+	if err := b.checkLimits(); err != nil {
 		return nil, err
+	}
+	if b.rdInd < b.length && b.buffer[b.rdInd] == 'N' {
+		b.rdInd++
+		return nil, nil
+	}
+	if b.rdInd >= b.length || !isMapForm(b.buffer[b.rdInd]) {
+		return nil, ErrNotMap
+	}
+	var obj interface{}
+	var err error
+	if b.buffer[b.rdInd] == hessian.BC_MAP {
+		obj, err = b.readTypedList()
 	} else {
-		tmpMap := obj.(map[string]interface{})
-		var strMap = make(map[string]string)
-		for k, v := range tmpMap {
-			strMap[k] = v.(string)
+		obj, err = hessian.NewGoHessian(nil, nil).ToObject2(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tmpMap, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil, ErrNotMap
+	}
+	var strMap = make(map[string]string)
+	for k, v := range tmpMap {
+		switch val := v.(type) {
+		case string:
+			strMap[k] = val
+		case nil:
+		default:
+			strMap[k] = fmt.Sprint(val)
 		}
-		return strMap, nil
 	}
+	return strMap, nil
 }
 
 //Read 实现io.Reader
@@ -219,6 +483,10 @@ func (b *ReadBuffer) Read(p []byte) (n int, err error) {
 	} else {
 		cpysize := b.length - b.rdInd
 		copy(p, b.buffer[b.rdInd:b.length])
+		if b.drained {
+			b.overread += cpysize
+		}
+		b.drained = true
 		b.rdInd = b.length - 1
 		return cpysize, nil
 	}
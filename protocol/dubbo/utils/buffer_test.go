@@ -0,0 +1,348 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeObjects(t assert.TestingT, objs ...interface{}) []byte {
+	var buffer WriteBuffer
+	buffer.Init(0)
+	for _, obj := range objs {
+		assert.NoError(t, buffer.WriteObject(obj))
+	}
+	return buffer.GetValidData()
+}
+
+func nestedList(depth int) interface{} {
+	var v interface{} = "leaf"
+	for i := 0; i < depth; i++ {
+		v = []interface{}{v}
+	}
+	return v
+}
+
+func TestScanner_ScanValue(t *testing.T) {
+	values := []interface{}{
+		nil, true, int32(1), int32(1000), int32(1 << 20), int64(100), float64(1.5), "hello",
+		[]interface{}{"a", int32(2)}, map[string]interface{}{"k": "v", "n": map[string]interface{}{"x": nil}},
+	}
+	for _, v := range values {
+		data := writeObjects(t, v)
+		s := newScanner(data, 0)
+		assert.NoError(t, s.scanValue(0))
+		assert.Equal(t, len(data), s.pos, "value %v", v)
+	}
+
+	data := writeObjects(t, "hello")
+	assert.Equal(t, ErrTruncatedValue, newScanner(data[:len(data)-1], 0).scanValue(0))
+}
+
+func TestReadBuffer_MaxDepth(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, nestedList(100)))
+	rbf.SetMaxDepth(32)
+	obj, err := rbf.ReadObject()
+	assert.Nil(t, obj)
+	assert.Equal(t, ErrMaxDepthExceeded, err)
+
+	rbf.SetBuffer(writeObjects(t, map[string]interface{}{"k": nestedList(32)}))
+	_, err = rbf.ReadObject()
+	assert.Equal(t, ErrMaxDepthExceeded, err)
+
+	rbf.SetBuffer(writeObjects(t, nestedList(32)))
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, nestedList(32), obj)
+}
+
+func TestReadBuffer_CheckNextSize(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, []interface{}{"hello", "world"}, "next"))
+	assert.Equal(t, ErrValueTooLarge, rbf.CheckNextSize(8))
+	assert.NoError(t, rbf.CheckNextSize(64))
+
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"hello", "world"}, obj)
+}
+
+type intoOrder struct {
+	ID    string
+	Count int32
+	Tags  []string
+}
+
+func TestReadBuffer_ReadObjectInto(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, intoOrder{"o-1", 3, []string{"a", "b"}}, []interface{}{"x", "y"}, int32(7)))
+
+	var order intoOrder
+	assert.NoError(t, rbf.ReadObjectInto(&order))
+	assert.Equal(t, intoOrder{"o-1", 3, []string{"a", "b"}}, order)
+
+	var list []string
+	assert.NoError(t, rbf.ReadObjectInto(&list))
+	assert.Equal(t, []string{"x", "y"}, list)
+
+	var n int64
+	assert.NoError(t, rbf.ReadObjectInto(&n))
+	assert.Equal(t, int64(7), n)
+
+	rbf.SetBuffer(writeObjects(t, "text"))
+	assert.Equal(t, ErrTypeMismatch, rbf.ReadObjectInto(&n))
+	assert.Equal(t, ErrInvalidTarget, rbf.ReadObjectInto(n))
+}
+
+type intoAccount struct {
+	UserName  string
+	AccountID int32
+}
+
+func TestReadBuffer_ReadObjectIntoRenamedFields(t *testing.T) {
+	RegisterJavaTypeFields("com.demo.Account", intoAccount{}, map[string]string{
+		"user_name":  "UserName",
+		"account_id": "AccountID",
+	})
+	defer UnregisterJavaType("com.demo.Account")
+	data := writeObjects(t, intoAccount{"tom", 42}, []interface{}{intoAccount{"ann", 7}})
+	assert.Contains(t, string(data), "user_name")
+	assert.Contains(t, string(data), "account_id")
+	assert.NotContains(t, string(data), "userName")
+
+	var rbf ReadBuffer
+	rbf.SetBuffer(data)
+	var account intoAccount
+	assert.NoError(t, rbf.ReadObjectInto(&account))
+	assert.Equal(t, intoAccount{"tom", 42}, account)
+
+	var accounts []intoAccount
+	assert.NoError(t, rbf.ReadObjectInto(&accounts))
+	assert.Equal(t, []intoAccount{{"ann", 7}}, accounts)
+}
+
+type objectAttachments struct {
+	Path    string
+	Timeout int32
+}
+
+func TestReadBuffer_ReadMapForms(t *testing.T) {
+	expected := map[string]string{"path": "com.demo.Hello", "timeout": "300"}
+	mapForm := writeObjects(t, map[string]interface{}{"path": "com.demo.Hello", "timeout": "300"})
+	objectForm := writeObjects(t, objectAttachments{Path: "com.demo.Hello", Timeout: 300})
+	assert.Equal(t, byte('H'), mapForm[0])
+	assert.Equal(t, byte('C'), objectForm[0])
+	typedForm := append(append([]byte{'M'}, writeObjects(t, "java.util.HashMap", "path", "com.demo.Hello", "timeout", int32(300))...), 'Z')
+	for _, data := range [][]byte{mapForm, typedForm, objectForm} {
+		var rbf ReadBuffer
+		rbf.SetBuffer(data)
+		m, err := rbf.ReadMap()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, m)
+	}
+
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, []interface{}{"path"}))
+	_, err := rbf.ReadMap()
+	assert.Equal(t, ErrNotMap, err)
+}
+
+type unknownPojo struct {
+	Name  string
+	Items []interface{}
+}
+
+type unknownItem struct {
+	Sku string
+}
+
+func TestReadBuffer_ReadObjectClassName(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, unknownPojo{"order", []interface{}{unknownItem{"a-1"}, "plain"}}))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		ClassKey: "unknownPojo",
+		"name":   "order",
+		"items":  []interface{}{map[string]interface{}{ClassKey: "unknownItem", "sku": "a-1"}, "plain"},
+	}, obj)
+
+	//forwarded maps are written without the class names
+	rbf.SetBuffer(writeObjects(t, obj))
+	forwarded, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "order",
+		"items": []interface{}{map[string]interface{}{"sku": "a-1"}, "plain"},
+	}, forwarded)
+	assert.Equal(t, "unknownPojo", obj.(map[string]interface{})[ClassKey])
+
+	rbf.SetBuffer(writeObjects(t, map[string]interface{}{"k": "v"}))
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"k": "v"}, obj)
+}
+
+func TestWriteBuffer_SpecialDoubles(t *testing.T) {
+	values := []interface{}{math.NaN(), math.Inf(1), math.Inf(-1)}
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, append(values, values)...))
+	for _, v := range values {
+		obj, err := rbf.ReadObject()
+		assert.NoError(t, err)
+		assertSameDouble(t, v.(float64), obj)
+	}
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	for i, v := range values {
+		assertSameDouble(t, v.(float64), obj.([]interface{})[i])
+	}
+}
+
+func assertSameDouble(t *testing.T, expected float64, actual interface{}) {
+	f, ok := actual.(float64)
+	assert.True(t, ok, "%v is not a double", actual)
+	if math.IsNaN(expected) {
+		assert.True(t, math.IsNaN(f))
+		assert.False(t, f == f)
+	} else {
+		assert.Equal(t, expected, f)
+	}
+}
+
+func TestReadBuffer_ReadByteE(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(nil)
+	_, err := rbf.ReadByteE()
+	assert.Equal(t, ErrTruncatedValue, err)
+
+	rbf.SetBuffer(writeObjects(t, int32(4), "text"))
+	b, err := rbf.ReadByteE()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(4), b)
+	_, err = rbf.ReadByteE()
+	assert.Equal(t, ErrNotByte, err)
+}
+
+func TestReadBuffer_TypedLists(t *testing.T) {
+	var rbf ReadBuffer
+	//a variable list of type [string holding a fixed list referring to the same type, then an int
+	data := append([]byte{'U', 0x07}, "[string"...)
+	data = append(data, 0x01, 'a', 0x71, 0x90, 0x01, 'b', 'Z', 0x95)
+	rbf.SetBuffer(data)
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", []interface{}{"b"}}, obj)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), obj)
+}
+
+func TestReadBuffer_OrderedSets(t *testing.T) {
+	tree := OrderedSet{Class: "java.util.TreeSet", Elements: []interface{}{"apple", "banana", "cherry"}}
+	linked := OrderedSet{Class: "java.util.LinkedHashSet", Elements: []interface{}{"zeta", int32(1), "alpha"}}
+	data := writeObjects(t, tree, linked)
+	assert.Equal(t, append([]byte{'V'}, writeObjects(t, "java.util.TreeSet")...), data[:19])
+
+	var rbf ReadBuffer
+	rbf.SetBuffer(data)
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, tree, obj)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, linked, obj)
+	assert.Equal(t, JavaSet, JavaTypeOf(linked))
+
+	//java writes short sets as compact typed lists, other sets are decoded into slices
+	rbf.SetBuffer(append([]byte{0x72}, writeObjects(t, "java.util.LinkedHashSet", "b", "a")...))
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, OrderedSet{Class: "java.util.LinkedHashSet", Elements: []interface{}{"b", "a"}}, obj)
+	rbf.SetBuffer(append([]byte{0x72}, writeObjects(t, "java.util.HashSet", "b", "a")...))
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"b", "a"}, obj)
+}
+
+func TestReadBuffer_GuavaImmutables(t *testing.T) {
+	var rbf ReadBuffer
+	list := append([]byte{0x72}, writeObjects(t, guavaPackage+"RegularImmutableList", "a", int32(1))...)
+	rbf.SetBuffer(list)
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", int32(1)}, obj)
+
+	//an immutable map holding an immutable list, and a nested map referring to the type of the outer one
+	data := append([]byte{'M'}, writeObjects(t, guavaPackage+"RegularImmutableMap", "a", int32(1), "b")...)
+	data = append(data, 0x71)
+	data = append(data, writeObjects(t, guavaPackage+"SingletonImmutableList", "x", "c")...)
+	data = append(data, 'M', 0x90)
+	data = append(data, writeObjects(t, "d", int32(2))...)
+	data = append(data, 'Z', 'Z', 0x95)
+	rbf.SetBuffer(data)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": int32(1),
+		"b": []interface{}{"x"},
+		"c": map[string]interface{}{"d": int32(2)},
+	}, obj)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), obj)
+
+	rbf.SetBuffer(append(append([]byte{'M'}, writeObjects(t, guavaPackage+"ImmutableSortedMap", int32(1), "a")...), 'Z'))
+	_, err = rbf.ReadObject()
+	assert.Equal(t, ErrNotString, err)
+}
+
+func TestReadBuffer_MaxStringLength(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetMaxStringLength(4)
+	rbf.SetBuffer(writeObjects(t, "abcd", nil, "abcde", int32(1)))
+	s, err := rbf.ReadStringE()
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", s)
+	s, err = rbf.ReadStringE()
+	assert.NoError(t, err)
+	assert.Equal(t, "", s)
+	_, err = rbf.ReadStringE()
+	assert.Equal(t, ErrStringTooLong, err)
+	assert.Equal(t, "", rbf.ReadString())
+
+	//the lengths of the chunks are added up, the last one claims 64k characters which do not follow
+	data := []byte{}
+	for i := 0; i < 3; i++ {
+		data = append(data, 'R', 0x00, 0x02, 'a', 'b')
+	}
+	data = append(data, 'S', 0xff, 0xff)
+	rbf.SetMaxStringLength(1 << 30)
+	rbf.SetBuffer(data)
+	assert.NoError(t, rbf.checkStringLength())
+	rbf.SetMaxStringLength(5)
+	_, err = rbf.ReadStringE()
+	assert.Equal(t, ErrStringTooLong, err)
+	rbf.SetBuffer([]byte{0x91})
+	_, err = rbf.ReadStringE()
+	assert.Equal(t, ErrNotString, err)
+}
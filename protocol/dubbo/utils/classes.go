@@ -0,0 +1,373 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"reflect"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//ClassKey is the key of the java class name in the maps which objects of unregistered classes decode into
+const ClassKey = "__class__"
+
+//withoutClassKeys returns v without the class names added to the maps decoded from objects, so that forwarded
+//values do not gain a field. v is only copied if it holds class names
+func withoutClassKeys(v interface{}) interface{} {
+	if !hasClassKeys(v) {
+		return v
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if k != ClassKey {
+				m[k] = withoutClassKeys(item)
+			}
+		}
+		return m
+	case []interface{}:
+		lst := make([]interface{}, len(val))
+		for i, item := range val {
+			lst[i] = withoutClassKeys(item)
+		}
+		return lst
+	}
+	return v
+}
+
+//hasClassKeys reports whether a map of v holds a class name
+func hasClassKeys(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if _, ok := val[ClassKey]; ok {
+			return true
+		}
+		for _, item := range val {
+			if hasClassKeys(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if hasClassKeys(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//classNode records the java classes of the objects nested in a decoded value
+type classNode struct {
+	class    string
+	keys     []string //field names of an object or keys of a map, nil for lists
+	children []*classNode
+	number   interface{} //value of an int or a long which gohessian misreads
+}
+
+//apply adds the class names to the maps of v which were decoded from objects and corrects their numbers
+func (n *classNode) apply(v interface{}) {
+	if n == nil {
+		return
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if n.class != "" {
+			val[ClassKey] = n.class
+		}
+		for i, key := range n.keys {
+			if child := n.children[i]; child.number != nil {
+				val[key] = child.number
+			} else {
+				child.apply(val[key])
+			}
+		}
+	case []interface{}:
+		for i, child := range n.children {
+			if i >= len(val) {
+				break
+			}
+			if child != nil && child.number != nil {
+				val[i] = child.number
+			} else {
+				child.apply(val[i])
+			}
+		}
+	case reflect.Value:
+		n.applyValue(val)
+	}
+}
+
+//applyValue corrects the numbers of the registered struct or the slice which gohessian decodes into rv
+func (n *classNode) applyValue(rv reflect.Value) {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Struct:
+		goFields, _ := registeredFieldNames()
+		fields := goFields[n.class]
+		for i, key := range n.keys {
+			if name, ok := fields[key]; ok {
+				key = name
+			}
+			fld := rv.FieldByName(key)
+			if !fld.IsValid() {
+				fld = rv.FieldByName(hessian.CapitalizeName(key))
+			}
+			n.children[i].setValue(fld)
+		}
+	case reflect.Slice:
+		for i, child := range n.children {
+			if i < rv.Len() {
+				child.setValue(rv.Index(i))
+			}
+		}
+	}
+}
+
+type classDef struct {
+	name   string
+	fields []string
+}
+
+//classWalker walks a hessian2 value and collects the classes of its objects
+type classWalker struct {
+	scanner
+	defs    []classDef
+	types   []string                     //types of the typed lists and maps, which later ones may refer to by index
+	names   map[string]map[string]string //new names of the fields of classes, by class
+	renamed []renamedDef                 //class definitions of classes in names
+}
+
+//walk returns the classes of the next value, nil if it holds no objects
+func (w *classWalker) walk() (*classNode, error) {
+	tag, err := w.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tagClasses[tag] {
+	case tagClassDef:
+		start := w.pos - 1
+		if err := w.readClassDef(); err != nil {
+			return nil, err
+		}
+		if def := w.defs[len(w.defs)-1]; w.names[def.name] != nil {
+			w.renamed = append(w.renamed, renamedDef{start, w.pos, def})
+		}
+		return w.walk()
+	case tagInstance:
+		return w.walkInstance(tag)
+	case tagMap:
+		return w.walkMap(tag)
+	case tagList:
+		return w.walkList(tag)
+	}
+	w.pos--
+	if node, ok, err := w.walkNumber(tag); ok {
+		return node, err
+	}
+	return nil, w.scanValue(0)
+}
+
+//readString reads the next value which is expected to be a string
+func (w *classWalker) readString() (string, error) {
+	start := w.pos
+	if err := w.scanValue(0); err != nil {
+		return "", err
+	}
+	var b ReadBuffer
+	b.SetBuffer(w.buf[start:w.pos])
+	obj, err := hessian.NewGoHessian(nil, nil).ToObject2(&b)
+	s, _ := obj.(string)
+	return s, err
+}
+
+//readType reads the type of a typed list or map, which is either a string or the index of an earlier type
+func (w *classWalker) readType() (string, error) {
+	tag, err := w.peek()
+	if err != nil {
+		return "", err
+	}
+	if !isStringTag(tag) {
+		idx, err := w.scanInt()
+		if err != nil {
+			return "", err
+		}
+		if idx < 0 || idx >= len(w.types) {
+			return "", ErrUnknownTag
+		}
+		return w.types[idx], nil
+	}
+	typ, err := w.readString()
+	if err == nil {
+		w.types = append(w.types, typ)
+	}
+	return typ, err
+}
+
+func (w *classWalker) readClassDef() error {
+	name, err := w.readString()
+	if err != nil {
+		return err
+	}
+	count, err := w.scanInt()
+	if err != nil {
+		return err
+	}
+	def := classDef{name, make([]string, count)}
+	for i := range def.fields {
+		if def.fields[i], err = w.readString(); err != nil {
+			return err
+		}
+	}
+	w.defs = append(w.defs, def)
+	return nil
+}
+
+func (w *classWalker) walkInstance(tag byte) (*classNode, error) {
+	idx := int(tag - hessian.BC_OBJECT_DIRECT)
+	if tag == hessian.BC_OBJECT {
+		var err error
+		if idx, err = w.scanInt(); err != nil {
+			return nil, err
+		}
+	}
+	if idx < 0 || idx >= len(w.defs) {
+		return nil, ErrUnknownTag
+	}
+	node := &classNode{class: w.defs[idx].name}
+	for _, field := range w.defs[idx].fields {
+		child, err := w.walk()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			node.keys = append(node.keys, field)
+			node.children = append(node.children, child)
+		}
+	}
+	return node, nil
+}
+
+func (w *classWalker) walkMap(tag byte) (*classNode, error) {
+	if tag == hessian.BC_MAP {
+		if _, err := w.readType(); err != nil {
+			return nil, err
+		}
+	}
+	node := &classNode{}
+	for {
+		if tag, err := w.peek(); err != nil || tag == hessian.BC_END {
+			w.pos++
+			return node.orNil(), err
+		}
+		key, err := w.readString()
+		if err != nil {
+			return nil, err
+		}
+		child, err := w.walk()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			node.keys = append(node.keys, key)
+			node.children = append(node.children, child)
+		}
+	}
+}
+
+//listSize reads the type and length of a list, -1 means a variable list ending with an end marker
+func (w *classWalker) listSize(tag byte) (int, error) {
+	typed := tag == hessian.BC_LIST_FIXED || tag == hessian.BC_LIST_VARIABLE ||
+		(tag >= hessian.BC_LIST_DIRECT && tag < hessian.BC_LIST_DIRECT_UNTYPED)
+	if typed {
+		if _, err := w.readType(); err != nil {
+			return 0, err
+		}
+	}
+	switch {
+	case tag >= hessian.BC_LIST_DIRECT_UNTYPED:
+		return int(tag - hessian.BC_LIST_DIRECT_UNTYPED), nil
+	case tag >= hessian.BC_LIST_DIRECT:
+		return int(tag - hessian.BC_LIST_DIRECT), nil
+	case tag == hessian.BC_LIST_FIXED || tag == hessian.BC_LIST_FIXED_UNTYPED:
+		return w.scanInt()
+	}
+	return -1, nil
+}
+
+func (w *classWalker) walkList(tag byte) (*classNode, error) {
+	size, err := w.listSize(tag)
+	if err != nil {
+		return nil, err
+	}
+	node := &classNode{}
+	for i := 0; i != size; i++ {
+		if size < 0 {
+			if tag, err := w.peek(); err != nil || tag == hessian.BC_END {
+				w.pos++
+				return node.orNil(), err
+			}
+		}
+		child, err := w.walk()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	return node.orNil(), nil
+}
+
+//orNil returns nil for the nodes of maps and lists which hold no objects
+func (n *classNode) orNil() *classNode {
+	for _, child := range n.children {
+		if child != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+//readHessian reads the next value with gohessian. The value is walked once before it is decoded, to find the
+//classes of its objects and the numbers gohessian misreads, which are applied to the decoded value, and the
+//class definitions of the classes registered with field names, which are decoded renamed to the go fields
+func (b *ReadBuffer) readHessian() (interface{}, error) {
+	goFields, _ := registeredFieldNames()
+	w := &classWalker{scanner: scanner{buf: b.buffer[b.rdInd:b.length]}, names: goFields}
+	node, walkErr := w.walk()
+	if walkErr == nil && node != nil && node.number != nil {
+		b.rdInd += w.pos
+		return node.number, nil
+	}
+	var obj interface{}
+	var err error
+	if walkErr == nil && len(w.renamed) > 0 {
+		var data []byte
+		if data, err = w.rename(); err != nil {
+			return nil, err
+		}
+		b.rdInd += w.pos
+		obj, err = hessian.NewGoHessian(registeredTypes(), nil).ToObject(data)
+	} else {
+		obj, err = hessian.NewGoHessian(registeredTypes(), nil).ToObject2(b)
+	}
+	if err == nil && walkErr == nil {
+		node.apply(obj)
+	}
+	return b.intern(obj), err
+}
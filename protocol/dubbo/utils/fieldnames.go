@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"reflect"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//goFieldNames maps the java classes registered with field names to the go field of every renamed java field
+var goFieldNames = make(map[string]map[string]string)
+
+//javaFieldNames maps the same classes to the java field of every renamed go field, as gohessian writes it
+var javaFieldNames = make(map[string]map[string]string)
+
+//RegisterJavaTypeFields is a function which maps a java class to the go struct of v like RegisterJavaType,
+//for classes whose field names differ from the go fields, such as user_name for UserName. fields maps the
+//java field names to the go field names, the other fields match by name. Types should be registered during init
+func RegisterJavaTypeFields(javaClass string, v interface{}, fields map[string]string) {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	goFields := make(map[string]string, len(fields))
+	javaFields := make(map[string]string, len(fields))
+	for javaField, goField := range fields {
+		goFields[javaField] = goField
+		javaFields[lowerFirst(goField)] = javaField
+	}
+	javaTypesMtx.Lock()
+	defer javaTypesMtx.Unlock()
+	registerJavaType(javaClass, typ)
+	goNames, javaNames := copyFieldNames(goFieldNames), copyFieldNames(javaFieldNames)
+	goNames[javaClass] = goFields
+	javaNames[javaClass] = javaFields
+	goFieldNames, javaFieldNames = goNames, javaNames
+}
+
+//registeredFieldNames returns goFieldNames and javaFieldNames, the maps must not be modified
+func registeredFieldNames() (map[string]map[string]string, map[string]map[string]string) {
+	javaTypesMtx.RLock()
+	defer javaTypesMtx.RUnlock()
+	return goFieldNames, javaFieldNames
+}
+
+//copyFieldNames returns a copy of field names, the names of a class are shared since they are never modified
+func copyFieldNames(names map[string]map[string]string) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(names))
+	for k, v := range names {
+		out[k] = v
+	}
+	return out
+}
+
+//lowerFirst returns the field name gohessian writes for the go field name
+func lowerFirst(name string) string {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return name
+	}
+	return string(name[0]+'a'-'A') + name[1:]
+}
+
+//renamedDef is the span of a class definition of the walked value, whose fields are renamed in the copy
+type renamedDef struct {
+	start int
+	end   int
+	def   classDef
+}
+
+//rename returns a copy of the value walked by w with the fields of its renamed class definitions replaced
+func (w *classWalker) rename() ([]byte, error) {
+	var out WriteBuffer
+	out.Init(w.pos)
+	last := 0
+	for _, r := range w.renamed {
+		out.WriteBytes(w.buf[last:r.start])
+		if err := writeClassDef(&out, r.def, w.names[r.def.name]); err != nil {
+			return nil, err
+		}
+		last = r.end
+	}
+	out.WriteBytes(w.buf[last:w.pos])
+	return out.GetValidData(), nil
+}
+
+//writeClassDef writes the class definition def with the fields in renamed replaced by their new names
+func writeClassDef(out *WriteBuffer, def classDef, renamed map[string]string) error {
+	w := newEnumWriter(out)
+	out.WriteBytes([]byte{hessian.BC_OBJECT_DEF})
+	if err := w.write(def.name, int32(len(def.fields))); err != nil {
+		return err
+	}
+	for _, field := range def.fields {
+		if name, ok := renamed[field]; ok {
+			field = name
+		}
+		if err := w.write(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//renameWritten renames the fields gohessian wrote since start to the java fields in names of the classes
+//registered with field names, the written bytes are only copied if they define such a class
+func (b *WriteBuffer) renameWritten(start int, names map[string]map[string]string) error {
+	w := &classWalker{scanner: scanner{buf: b.buffer[start:b.wrInd]}, names: names}
+	if _, err := w.walk(); err != nil || len(w.renamed) == 0 {
+		return err
+	}
+	data, err := w.rename()
+	if err != nil {
+		return err
+	}
+	b.wrInd = start
+	b.WriteBytes(data)
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"strings"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//guavaPackage is the package of the guava collections
+const guavaPackage = "com.google.common.collect."
+
+//isGuavaImmutable reports whether class is one of the guava immutable collections, such as
+//com.google.common.collect.RegularImmutableMap, which java writes as typed lists and maps
+func isGuavaImmutable(class string) bool {
+	return strings.HasPrefix(class, guavaPackage) && strings.Contains(class[len(guavaPackage):], "Immutable")
+}
+
+//isImmutableMap reports whether the next value of buffer is a guava immutable map
+func (b *ReadBuffer) isImmutableMap() bool {
+	if b.rdInd >= b.length || b.buffer[b.rdInd] != hessian.BC_MAP {
+		return false
+	}
+	w := &classWalker{scanner: scanner{buf: b.buffer[b.rdInd+1 : b.length]}}
+	class, err := w.readType()
+	return err == nil && isGuavaImmutable(class)
+}
+
+//copyImmutableMap copies the entries of a guava immutable map as an untyped map, gohessian only reads
+//one entry of typed maps. The keys must be strings like the ones of every untyped map gohessian reads
+func (u *listUntyper) copyImmutableMap() error {
+	u.out = append(u.out, hessian.BC_MAP_UNTYPED)
+	for {
+		tag, err := u.peek()
+		if err != nil {
+			return err
+		}
+		if tag == hessian.BC_END {
+			u.pos++
+			u.out = append(u.out, hessian.BC_END)
+			return nil
+		}
+		if !isStringTag(tag) {
+			return ErrNotString
+		}
+		if err := u.copyValue(); err != nil { //key
+			return err
+		}
+		if err := u.copyValue(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//walkNumber reads the int or the long starting with tag if gohessian misreads it, ok is false for the other
+//values, which are left unread
+func (w *classWalker) walkNumber(tag byte) (node *classNode, ok bool, err error) {
+	if misreadInt(tag) {
+		v, err := w.scanInt()
+		return &classNode{number: int32(v)}, true, err
+	}
+	if misreadLong(tag) {
+		v, err := w.scanLong()
+		return &classNode{number: v}, true, err
+	}
+	return nil, false, nil
+}
+
+//misreadInt reports whether gohessian misreads the int starting with tag: it takes the compact negative ints for
+//positive ones and swaps the low bytes of three-byte ints
+func misreadInt(tag byte) bool {
+	return tag >= 0x80 && tag < hessian.BC_INT_ZERO || tag >= 0xc0 && tag < hessian.BC_INT_BYTE_ZERO ||
+		tag >= 0xd0 && tag <= 0xd7
+}
+
+//misreadLong reports whether gohessian misreads the long starting with tag: it takes the compact negative longs
+//for positive ones, swaps the low bytes of three-byte longs, reads the int form as an int and misplaces the
+//bytes of eight-byte longs
+func misreadLong(tag byte) bool {
+	return tag >= 0xd8 && tag < hessian.BC_LONG_ZERO || tag >= 0xf0 && tag < hessian.BC_LONG_BYTE_ZERO ||
+		tag >= 0x38 && tag <= 0x3f || tag == hessian.BC_LONG_INT || tag == hessian.BC_LONG
+}
+
+//setValue sets rv to the number of n, or corrects the numbers of the values nested in rv
+func (n *classNode) setValue(rv reflect.Value) {
+	if n == nil || !rv.IsValid() || !rv.CanSet() {
+		return
+	}
+	if n.number == nil {
+		n.applyValue(rv)
+		return
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(reflect.ValueOf(n.number).Int())
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(n.number))
+	}
+}
+
+//writeLong writes v in the direct form of longs, the int form or the eight-byte form, as gohessian writes the
+//negative direct longs and the eight-byte form with bytes misplaced
+func (b *WriteBuffer) writeLong(v int64) error {
+	switch {
+	case v >= hessian.LONG_DIRECT_MIN && v <= int64(hessian.LONG_DIRECT_MAX):
+		b.WriteBytes([]byte{byte(int64(hessian.BC_LONG_ZERO) + v)})
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		buf := []byte{hessian.BC_LONG_INT, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(buf[1:], uint32(v))
+		b.WriteBytes(buf)
+	default:
+		buf := make([]byte, 9)
+		buf[0] = hessian.BC_LONG
+		binary.BigEndian.PutUint64(buf[1:], uint64(v))
+		b.WriteBytes(buf)
+	}
+	return nil
+}
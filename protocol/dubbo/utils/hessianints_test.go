@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readObjects(t *testing.T, data []byte, count int) []interface{} {
+	var rbf ReadBuffer
+	rbf.SetBuffer(data)
+	objs := make([]interface{}, count)
+	for i := range objs {
+		var err error
+		objs[i], err = rbf.ReadObject()
+		assert.NoError(t, err)
+	}
+	return objs
+}
+
+func TestWriteBuffer_NegativeIntsRoundTrip(t *testing.T) {
+	//one value of every compact form and of the four-byte form
+	ints := []int32{-1, -16, -17, -2048, -2049, -262144, -262145, math.MinInt32, 47, 2047, 262143, math.MaxInt32}
+	for _, v := range ints {
+		assert.Equal(t, []interface{}{v}, readObjects(t, writeObjects(t, v), 1), "int %d", v)
+	}
+
+	nested := []interface{}{
+		map[string]interface{}{"a": int32(-17), "b": int32(-2049), "c": int32(-262145)},
+		[]interface{}{int32(-1), int32(-2048), int32(math.MinInt32)},
+	}
+	assert.Equal(t, nested, readObjects(t, writeObjects(t, nested...), len(nested)))
+}
+
+func TestWriteBuffer_LongsOutsideIntRoundTrip(t *testing.T) {
+	longs := []int64{math.MaxInt32 + 1, math.MinInt32 - 1, 1 << 40, -1 << 40, math.MaxInt64, math.MinInt64}
+	for _, v := range longs {
+		data := writeObjects(t, v)
+		assert.Len(t, data, 9, "long %d is written in the eight-byte form", v)
+		assert.Equal(t, []interface{}{v}, readObjects(t, data, 1), "long %d", v)
+	}
+
+	//the longs in the int range keep the compact forms java reads
+	assert.Equal(t, []byte{0xd8}, writeObjects(t, int64(-8)))
+	assert.Equal(t, []byte{0x59, 0xff, 0xff, 0xff, 0xf7}, writeObjects(t, int64(-9)))
+	assert.Equal(t, []interface{}{int64(-9), int64(math.MinInt32)},
+		readObjects(t, writeObjects(t, int64(-9), int64(math.MinInt32)), 2))
+}
+
+//longRange is mapped to the java class com.demo.Range { long min; long max; int step; }
+type longRange struct {
+	Min  int64
+	Max  int64
+	Step int32
+}
+
+func TestReadBuffer_NestedLongsOutsideInt(t *testing.T) {
+	//java writes longs nested in maps, lists and objects in the eight-byte form outside the int range
+	long8 := func(v int64) []byte {
+		data := []byte{'L', 0, 0, 0, 0, 0, 0, 0, 0}
+		for i := 0; i < 8; i++ {
+			data[8-i] = byte(v >> uint(8*i))
+		}
+		return data
+	}
+	var data []byte
+	data = append(data, 'H', 0x01, 'a')
+	data = append(data, long8(-1<<40)...)
+	data = append(data, 0x01, 'b')
+	data = append(data, long8(math.MaxInt32+1)...)
+	data = append(data, 'Z', 0x7a)
+	data = append(data, long8(math.MinInt64)...)
+	data = append(data, long8(math.MinInt32-1)...)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"a": int64(-1 << 40), "b": int64(math.MaxInt32 + 1)},
+		[]interface{}{int64(math.MinInt64), int64(math.MinInt32 - 1)},
+	}, readObjects(t, data, 2))
+
+	RegisterJavaType("com.demo.Range", longRange{})
+	defer UnregisterJavaType("com.demo.Range")
+	data = []byte{'C', 0x0e}
+	data = append(data, "com.demo.Range"...)
+	data = append(data, 0x93, 0x03, 'm', 'i', 'n', 0x03, 'm', 'a', 'x', 0x04, 's', 't', 'e', 'p', 0x60)
+	data = append(data, long8(-1<<40)...)
+	data = append(data, long8(math.MaxInt64)...)
+	data = append(data, 0xc7, 0xef)
+	obj, err := ConvertByJavaType("Lcom/demo/Range;", readObjects(t, data, 1)[0])
+	assert.NoError(t, err)
+	assert.Equal(t, &longRange{-1 << 40, math.MaxInt64, -17}, obj)
+}
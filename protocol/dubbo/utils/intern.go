@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"sync"
+)
+
+//StringInterner is a bounded table which makes identical decoded strings share one backing array
+type StringInterner struct {
+	mtx     sync.RWMutex
+	size    int
+	strings map[string]string
+}
+
+//NewStringInterner is a function which creates an interner holding at most size strings
+func NewStringInterner(size int) *StringInterner {
+	return &StringInterner{size: size, strings: make(map[string]string)}
+}
+
+//Intern is a method which returns the shared copy of s, new strings are not kept once the table is full
+func (in *StringInterner) Intern(s string) string {
+	in.mtx.RLock()
+	shared, ok := in.strings[s]
+	in.mtx.RUnlock()
+	if ok {
+		return shared
+	}
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	if shared, ok := in.strings[s]; ok {
+		return shared
+	}
+	if len(in.strings) < in.size {
+		in.strings[s] = s
+	}
+	return s
+}
+
+//Len is a method which returns the number of interned strings
+func (in *StringInterner) Len() int {
+	in.mtx.RLock()
+	defer in.mtx.RUnlock()
+	return len(in.strings)
+}
@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestReadBuffer_Intern(t *testing.T) {
+	interner := NewStringInterner(2)
+	data := writeObjects(t, "ACTIVE", "ACTIVE", "INACTIVE", "DELETED", "DELETED")
+	var rbf ReadBuffer
+	rbf.SetBuffer(data)
+	rbf.SetInterner(interner)
+
+	values := make([]string, 5)
+	for i := range values {
+		obj, err := rbf.ReadObject()
+		assert.NoError(t, err)
+		values[i] = obj.(string)
+	}
+	assert.Equal(t, []string{"ACTIVE", "ACTIVE", "INACTIVE", "DELETED", "DELETED"}, values)
+	assert.Equal(t, stringData(values[0]), stringData(values[1]))
+	//the table is full, so DELETED is not interned
+	assert.NotEqual(t, stringData(values[3]), stringData(values[4]))
+	assert.Equal(t, 2, interner.Len())
+}
+
+func benchmarkRetainedStrings(b *testing.B, interner *StringInterner) {
+	value := strings.Repeat("status-code-", 16)
+	data := writeObjects(b, value)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	retained := make([]interface{}, 0, b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rbf ReadBuffer
+		rbf.SetBuffer(data)
+		rbf.SetInterner(interner)
+		obj, _ := rbf.ReadObject()
+		retained = append(retained, obj)
+	}
+	b.StopTimer()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	b.Logf("retained %d bytes for %d strings", int64(after.HeapAlloc)-int64(before.HeapAlloc), len(retained))
+	runtime.KeepAlive(retained)
+}
+
+func BenchmarkReadBuffer_RetainedStrings(b *testing.B) {
+	b.Run("plain", func(b *testing.B) {
+		benchmarkRetainedStrings(b, nil)
+	})
+	b.Run("interned", func(b *testing.B) {
+		benchmarkRetainedStrings(b, NewStringInterner(16))
+	})
+}
@@ -0,0 +1,138 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+//atomicPackage is the package of the java atomic wrappers
+const atomicPackage = "java.util.concurrent.atomic."
+
+//atomicIntegerHandle has the serialized field of java.util.concurrent.atomic.AtomicInteger
+type atomicIntegerHandle struct {
+	Value int32
+}
+
+//atomicLongHandle has the serialized field of java.util.concurrent.atomic.AtomicLong
+type atomicLongHandle struct {
+	Value int64
+}
+
+//longAdderHandle has the serialized field of the proxy java.util.concurrent.atomic.LongAdder is written as
+type longAdderHandle struct {
+	Value int64
+}
+
+//doubleAdderHandle has the serialized field of the proxy java.util.concurrent.atomic.DoubleAdder is written as,
+//gohessian can not write double fields, so the handle is written by writeDoubleAdder
+type doubleAdderHandle struct {
+	Value float64
+}
+
+//atomicValue reads the value wrapped by a decoded atomic wrapper
+func atomicValue(v interface{}) (int64, bool) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		v = obj["value"]
+	}
+	switch val := v.(type) {
+	case int32:
+		return int64(val), true
+	case int64:
+		return val, true
+	case int:
+		return int64(val), true
+	}
+	return 0, false
+}
+
+func toAtomicInteger(v interface{}) (interface{}, error) {
+	i, ok := atomicValue(v)
+	if !ok {
+		return nil, &BaseError{"AtomicInteger has no int value"}
+	}
+	return int32(i), nil
+}
+
+func toAtomicLong(v interface{}) (interface{}, error) {
+	i, ok := atomicValue(v)
+	if !ok {
+		return nil, &BaseError{"AtomicLong has no long value"}
+	}
+	return i, nil
+}
+
+func toAtomicIntegerHandle(v interface{}) interface{} {
+	if i, ok := atomicValue(v); ok {
+		return atomicIntegerHandle{int32(i)}
+	}
+	return v
+}
+
+func toAtomicLongHandle(v interface{}) interface{} {
+	if i, ok := atomicValue(v); ok {
+		return atomicLongHandle{i}
+	}
+	return v
+}
+
+//adderDouble reads the sum wrapped by a decoded DoubleAdder, hessian writes whole doubles in the int forms
+func adderDouble(v interface{}) (float64, bool) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		v = obj["value"]
+	}
+	if f, ok := v.(float64); ok {
+		return f, true
+	}
+	i, ok := atomicValue(v)
+	return float64(i), ok
+}
+
+func toLongAdder(v interface{}) (interface{}, error) {
+	i, ok := atomicValue(v)
+	if !ok {
+		return nil, &BaseError{"LongAdder has no long sum"}
+	}
+	return i, nil
+}
+
+func toDoubleAdder(v interface{}) (interface{}, error) {
+	f, ok := adderDouble(v)
+	if !ok {
+		return nil, &BaseError{"DoubleAdder has no double sum"}
+	}
+	return f, nil
+}
+
+func toLongAdderHandle(v interface{}) interface{} {
+	if i, ok := atomicValue(v); ok {
+		return longAdderHandle{i}
+	}
+	return v
+}
+
+func toDoubleAdderHandle(v interface{}) interface{} {
+	if f, ok := adderDouble(v); ok {
+		return doubleAdderHandle{f}
+	}
+	return v
+}
+
+//writeDoubleAdder writes h as the serialization proxy of java.util.concurrent.atomic.DoubleAdder
+func (b *WriteBuffer) writeDoubleAdder(h doubleAdderHandle) error {
+	if err := newEnumWriter(b).writeInstance(javaClassName("doubleAdderHandle"), "value"); err != nil {
+		return err
+	}
+	return b.writeDouble(h.Value)
+}
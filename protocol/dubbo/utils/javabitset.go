@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//bitSetHeader starts the class definition of java.util.BitSet, whose only serialized field is the long array words
+var bitSetHeader = []byte("C\x10java.util.BitSet")
+
+//BitSet is the go form of java.util.BitSet, bit i of the set is bit i%64 of word i/64 as in java
+type BitSet []uint64
+
+//NewBitSet is a function which creates a bit set with bits set
+func NewBitSet(bits ...int) BitSet {
+	var s BitSet
+	for _, bit := range bits {
+		for len(s) <= bit/64 {
+			s = append(s, 0)
+		}
+		s[bit/64] |= 1 << uint(bit%64)
+	}
+	return s
+}
+
+//Test is a method which reports whether bit is set
+func (s BitSet) Test(bit int) bool {
+	return bit >= 0 && bit/64 < len(s) && s[bit/64]&(1<<uint(bit%64)) != 0
+}
+
+//toBitSet converts a decoded java.util.BitSet into a bit set
+func toBitSet(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case BitSet:
+		return val, nil
+	case map[string]interface{}:
+		words, _ := val["words"].([]interface{})
+		s := make(BitSet, len(words))
+		for i, word := range words {
+			w, ok := word.(int64)
+			if !ok {
+				return nil, &BaseError{"BitSet has no long words"}
+			}
+			s[i] = uint64(w)
+		}
+		return s, nil
+	}
+	return nil, &BaseError{"BitSet is not an object"}
+}
+
+//scanLong reads a long in any of its compact forms
+func (s *scanner) scanLong() (int64, error) {
+	tag, err := s.next()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case tag >= 0xd8 && tag <= 0xef:
+		return int64(tag) - 0xe0, nil
+	case tag >= 0xf0:
+		b, err := s.next()
+		return (int64(tag)-0xf8)<<8 + int64(b), err
+	case tag >= 0x38 && tag <= 0x3f:
+		if err := s.skip(2); err != nil {
+			return 0, err
+		}
+		return (int64(tag)-0x3c)<<16 + int64(s.buf[s.pos-2])<<8 + int64(s.buf[s.pos-1]), nil
+	case tag == hessian.BC_LONG_INT:
+		if err := s.skip(4); err != nil {
+			return 0, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(s.buf[s.pos-4:]))), nil
+	case tag == hessian.BC_LONG:
+		if err := s.skip(8); err != nil {
+			return 0, err
+		}
+		return int64(binary.BigEndian.Uint64(s.buf[s.pos-8:])), nil
+	}
+	return 0, ErrUnknownTag
+}
+
+//readWords reads the long array of a bit set
+func (w *classWalker) readWords() (BitSet, error) {
+	tag, err := w.next()
+	if err != nil || tag == hessian.BC_NULL {
+		return nil, err
+	}
+	if tagClasses[tag] != tagList {
+		return nil, ErrUnknownTag
+	}
+	size, err := w.listSize(tag)
+	if err != nil {
+		return nil, err
+	}
+	s := BitSet{}
+	for i := 0; i != size; i++ {
+		if tag, err := w.peek(); err != nil || (size < 0 && tag == hessian.BC_END) {
+			w.pos++
+			return s, err
+		}
+		word, err := w.scanLong()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, uint64(word))
+	}
+	return s, nil
+}
+
+//isBitSet reports whether the next value of buffer is a java.util.BitSet
+func (b *ReadBuffer) isBitSet() bool {
+	return bytes.HasPrefix(b.buffer[b.rdInd:b.length], bitSetHeader)
+}
+
+//readBitSet reads a java.util.BitSet. gohessian misreads most compact forms of longs, so the words are read here
+func (b *ReadBuffer) readBitSet() (interface{}, error) {
+	w := &classWalker{scanner: scanner{buf: b.buffer[b.rdInd:b.length]}}
+	w.pos = 1
+	if err := w.readClassDef(); err != nil {
+		return nil, err
+	}
+	if tag, err := w.next(); err != nil || tag != hessian.BC_OBJECT_DIRECT {
+		return nil, ErrUnknownTag
+	}
+	var s BitSet
+	for _, field := range w.defs[0].fields {
+		var err error
+		if field == "words" {
+			s, err = w.readWords()
+		} else {
+			err = w.scanValue(0)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	b.rdInd += w.pos
+	return s, nil
+}
+
+//writeBitSet writes s as a java.util.BitSet, without the zero words at its end like java.
+//gohessian can not write longs, so the object is written here
+func (b *WriteBuffer) writeBitSet(s BitSet) error {
+	for len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	w := newEnumWriter(b)
+	if err := w.writeInstance("java.util.BitSet", "words"); err != nil {
+		return err
+	}
+	if len(s) < 8 {
+		b.WriteBytes([]byte{hessian.BC_LIST_DIRECT + byte(len(s))})
+		if err := w.write("[long"); err != nil {
+			return err
+		}
+	} else {
+		b.WriteBytes([]byte{hessian.BC_LIST_FIXED})
+		if err := w.write("[long"); err != nil {
+			return err
+		}
+		buf := []byte{hessian.BC_INT, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(buf[1:], uint32(len(s)))
+		b.WriteBytes(buf)
+	}
+	buf := make([]byte, 9)
+	buf[0] = hessian.BC_LONG
+	for _, word := range s {
+		binary.BigEndian.PutUint64(buf[1:], word)
+		b.WriteBytes(buf)
+	}
+	return nil
+}
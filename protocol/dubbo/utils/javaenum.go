@@ -0,0 +1,236 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//ErrNotEnum is returned when a value expected to be an enum constant is not one
+var ErrNotEnum = &BaseError{"hessian value is not an enum constant"}
+
+//enumSetHandler is the class hessian-lite serializes java.util.EnumSet with,
+//it holds the enum class and an array of the constants
+const enumSetHandler = hessianPackage + "EnumSetHandler"
+
+//enumMapHeader starts a java.util.EnumMap, a typed map whose keys are enum constants
+var enumMapHeader = []byte("M\x11java.util.EnumMap")
+
+//toEnum converts a decoded enum constant, an object whose only field is the name of the constant
+func toEnum(v interface{}) (JavaEnum, error) {
+	switch val := v.(type) {
+	case JavaEnum:
+		return val, nil
+	case string:
+		return JavaEnum{Name: val}, nil
+	case map[string]interface{}:
+		name, ok := val["name"].(string)
+		if !ok {
+			return JavaEnum{}, ErrNotEnum
+		}
+		class, _ := val[ClassKey].(string)
+		return JavaEnum{class, name}, nil
+	}
+	return JavaEnum{}, ErrNotEnum
+}
+
+//toEnumSet converts a decoded java.util.EnumSet, the handle of hessian-lite or a list of the constants
+func toEnumSet(v interface{}) (interface{}, error) {
+	class := ""
+	if handle, ok := v.(map[string]interface{}); ok {
+		if typ, ok := handle["type"].(map[string]interface{}); ok {
+			class, _ = typ["name"].(string)
+		}
+		v = handle["objects"]
+	}
+	switch val := v.(type) {
+	case []JavaEnum:
+		return val, nil
+	case nil:
+		return []JavaEnum{}, nil
+	case []interface{}:
+		set := make([]JavaEnum, len(val))
+		for i, item := range val {
+			e, err := toEnum(item)
+			if err != nil {
+				return nil, err
+			}
+			if e.Class == "" {
+				e.Class = class
+			}
+			set[i] = e
+		}
+		return set, nil
+	}
+	return nil, &BaseError{"EnumSet has no constants"}
+}
+
+//toEnumMap converts a decoded java.util.EnumMap into a map keyed by the names of the constants
+func toEnumMap(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		delete(val, ClassKey)
+		return val, nil
+	case map[JavaEnum]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for e, item := range val {
+			m[e.Name] = item
+		}
+		return m, nil
+	}
+	return nil, &BaseError{"EnumMap is not a map"}
+}
+
+//isEnumMap reports whether the next value of buffer is a java.util.EnumMap
+func (b *ReadBuffer) isEnumMap() bool {
+	return bytes.HasPrefix(b.buffer[b.rdInd:b.length], enumMapHeader)
+}
+
+//readEnumMap reads a java.util.EnumMap into a map keyed by the names of the constants.
+//gohessian can not decode maps with object keys, so the entries are read here with one
+//decoder, which keeps the class definitions shared by the keys and the values
+func (b *ReadBuffer) readEnumMap() (interface{}, error) {
+	b.rdInd += len(enumMapHeader)
+	d := hessian.NewDecoder(b, registeredTypes())
+	m := make(map[string]interface{})
+	for {
+		if b.rdInd >= b.length {
+			return nil, ErrTruncatedValue
+		}
+		if b.buffer[b.rdInd] == hessian.BC_END {
+			b.rdInd++
+			return m, nil
+		}
+		key, err := d.ReadObject()
+		if err != nil {
+			return nil, err
+		}
+		e, err := toEnum(key)
+		if err != nil {
+			return nil, err
+		}
+		if m[e.Name], err = d.ReadObject(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//enumWriter writes enum constants, it numbers the class definitions of the value being written
+type enumWriter struct {
+	b    *WriteBuffer
+	gh   hessian.Serializer
+	defs map[string]int
+}
+
+func newEnumWriter(b *WriteBuffer) *enumWriter {
+	return &enumWriter{b, hessian.NewGoHessian(nil, nil), make(map[string]int)}
+}
+
+func (w *enumWriter) write(values ...interface{}) error {
+	for _, v := range values {
+		if err := w.gh.ToBytes2(v, w.b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//writeInstance starts an object of class, the class is defined with fields the first time it is written
+func (w *enumWriter) writeInstance(class string, fields ...string) error {
+	idx, ok := w.defs[class]
+	if !ok {
+		idx = len(w.defs)
+		w.defs[class] = idx
+		w.b.WriteBytes([]byte{hessian.BC_OBJECT_DEF})
+		if err := w.write(class, int32(len(fields))); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if err := w.write(field); err != nil {
+				return err
+			}
+		}
+	}
+	if idx <= 0x0f {
+		w.b.WriteBytes([]byte{hessian.BC_OBJECT_DIRECT + byte(idx)})
+		return nil
+	}
+	w.b.WriteBytes([]byte{hessian.BC_OBJECT})
+	return w.write(int32(idx))
+}
+
+func (w *enumWriter) writeEnum(e JavaEnum) error {
+	if err := w.writeInstance(e.Class, "name"); err != nil {
+		return err
+	}
+	return w.write(e.Name)
+}
+
+//writeEnumSet writes set as the handle of hessian-lite, the class of an empty set is unknown and written as null
+func (b *WriteBuffer) writeEnumSet(set []JavaEnum) error {
+	w := newEnumWriter(b)
+	if err := w.writeInstance(enumSetHandler, "type", "objects"); err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		b.WriteBytes([]byte{hessian.BC_NULL})
+	} else if err := w.writeInstance("java.lang.Class", "name"); err != nil {
+		return err
+	} else if err := w.write(set[0].Class); err != nil {
+		return err
+	}
+	if len(set) < 8 {
+		b.WriteBytes([]byte{hessian.BC_LIST_DIRECT_UNTYPED + byte(len(set))})
+	} else {
+		b.WriteBytes([]byte{hessian.BC_LIST_FIXED_UNTYPED})
+		if err := w.write(int32(len(set))); err != nil {
+			return err
+		}
+	}
+	for _, e := range set {
+		if err := w.writeEnum(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//writeEnumMap writes m as a java.util.EnumMap ordered by the names of the constants. The values
+//are written by gohessian which numbers class definitions from zero, so values holding objects are not supported
+func (b *WriteBuffer) writeEnumMap(m map[JavaEnum]interface{}) error {
+	keys := make([]JavaEnum, 0, len(m))
+	for e := range m {
+		keys = append(keys, e)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	w := newEnumWriter(b)
+	b.WriteBytes(enumMapHeader)
+	for _, e := range keys {
+		if err := w.writeEnum(e); err != nil {
+			return err
+		}
+		if err := b.WriteObject(m[e]); err != nil {
+			return err
+		}
+	}
+	b.WriteBytes([]byte{hessian.BC_END})
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+//ExternalReader reads an object of an Externalizable class from the data its writeExternal method wrote
+type ExternalReader func(in *ObjectInput) (interface{}, error)
+
+//externalReaders are the readers of Externalizable classes by class name
+var externalReaders = make(map[string]ExternalReader)
+
+//RegisterExternalReader is a function which makes JavaSerializer read the objects of the Externalizable class
+//javaClass with reader, the data of other Externalizable classes is skipped. Readers should be registered
+//during init
+func RegisterExternalReader(javaClass string, reader ExternalReader) {
+	externalReaders[javaClass] = reader
+}
+
+//ObjectInput is a struct which reads the data written by the writeExternal method of an Externalizable class,
+//like java.io.ObjectInput. Primitives and strings written with writeUTF are read from the block data, objects
+//are read from between the blocks
+type ObjectInput struct {
+	r *javaStream
+}
+
+//readExternal reads an externalizable object with reader, the data reader leaves is skipped
+func (r *javaStream) readExternal(reader ExternalReader) (interface{}, error) {
+	v, err := reader(&ObjectInput{r: r})
+	if err != nil {
+		return nil, err
+	}
+	return v, r.skipAnnotation()
+}
+
+//ReadBytes is a method which reads the next n bytes of block data
+func (in *ObjectInput) ReadBytes(n int) ([]byte, error) {
+	return in.r.readBlock(n)
+}
+
+//ReadBoolean is a method which reads a boolean written with writeBoolean
+func (in *ObjectInput) ReadBoolean() (bool, error) {
+	data, err := in.ReadBytes(1)
+	if err != nil {
+		return false, err
+	}
+	return data[0] != 0, nil
+}
+
+//ReadInt is a method which reads an int written with writeInt
+func (in *ObjectInput) ReadInt() (int32, error) {
+	data, err := in.ReadBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(data)), nil
+}
+
+//ReadLong is a method which reads a long written with writeLong
+func (in *ObjectInput) ReadLong() (int64, error) {
+	data, err := in.ReadBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(data)), nil
+}
+
+//ReadDouble is a method which reads a double written with writeDouble
+func (in *ObjectInput) ReadDouble() (float64, error) {
+	v, err := in.ReadLong()
+	return math.Float64frombits(uint64(v)), err
+}
+
+//ReadUTF is a method which reads a string written with writeUTF, in modified utf-8 which is read as utf-8
+func (in *ObjectInput) ReadUTF() (string, error) {
+	return in.r.readBlockUTF()
+}
+
+//ReadObject is a method which reads an object written with writeObject, it must follow the whole block data
+//written before it
+func (in *ObjectInput) ReadObject() (interface{}, error) {
+	if len(in.r.block) > 0 {
+		return nil, ErrJavaSerialization
+	}
+	return in.r.readContent()
+}
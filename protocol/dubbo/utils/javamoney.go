@@ -0,0 +1,174 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"strings"
+)
+
+//javaBigDecimal is the class of the amounts of money types, hessian-lite writes it with its decimal string
+const javaBigDecimal = "java.math.BigDecimal"
+
+//Money is the go form of java money types, such as JodaMoney and javax.money amounts. Amount is the decimal
+//string of the BigDecimal amount, so no precision is lost
+type Money struct {
+	Amount   string
+	Currency Currency
+}
+
+//MoneyType describes where a java money class holds its amount and currency. Amount is the path to the
+//BigDecimal field, Currency is the path to the currency code, the objects on the paths have the classes of Classes
+type MoneyType struct {
+	Class    string
+	Amount   string
+	Currency string
+	Classes  map[string]string
+}
+
+var (
+	//JodaMoney is the layout of org.joda.money.Money
+	JodaMoney = &MoneyType{
+		Class:    "org.joda.money.Money",
+		Amount:   "money.amount",
+		Currency: "money.currency.code",
+		Classes: map[string]string{
+			"money":          "org.joda.money.BigMoney",
+			"money.currency": "org.joda.money.CurrencyUnit",
+		},
+	}
+	//JodaBigMoney is the layout of org.joda.money.BigMoney
+	JodaBigMoney = &MoneyType{
+		Class:    "org.joda.money.BigMoney",
+		Amount:   "amount",
+		Currency: "currency.code",
+		Classes:  map[string]string{"currency": "org.joda.money.CurrencyUnit"},
+	}
+	//MonetaMoney is the layout of org.javamoney.moneta.Money, the javax.money reference implementation
+	MonetaMoney = &MoneyType{
+		Class:    "org.javamoney.moneta.Money",
+		Amount:   "number",
+		Currency: "currency.baseCurrency.value",
+		Classes: map[string]string{
+			"currency":              "org.javamoney.moneta.spi.JDKCurrencyAdapter",
+			"currency.baseCurrency": "java.util.Currency",
+		},
+	}
+)
+
+//moneyValue is a Money to be written as an object of a money type
+type moneyValue struct {
+	typ   *MoneyType
+	money Money
+}
+
+//RegisterMoneyType is a function which decodes the objects of the money class of t into Money, and encodes
+//Money as the class where it is declared with its java type. Types should be registered during init
+func RegisterMoneyType(t *MoneyType) {
+	desc := "L" + strings.Replace(t.Class, ".", "/", -1) + ";"
+	javaTypeConverters[desc] = t.toMoney
+	javaValueConverters[desc] = func(v interface{}) interface{} {
+		if m, ok := v.(Money); ok {
+			return moneyValue{t, m}
+		}
+		return v
+	}
+}
+
+func (t *MoneyType) toMoney(v interface{}) (interface{}, error) {
+	if m, ok := v.(Money); ok {
+		return m, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, &BaseError{t.Class + " is not an object"}
+	}
+	amount, ok := stringValue(pathValue(obj, t.Amount))
+	if !ok {
+		return nil, &BaseError{t.Class + " has no amount"}
+	}
+	code, ok := stringValue(pathValue(obj, t.Currency))
+	if !ok {
+		return nil, &BaseError{t.Class + " has no currency"}
+	}
+	return Money{Amount: amount, Currency: Currency(code)}, nil
+}
+
+//pathValue reads the field at the dotted path of decoded objects
+func pathValue(obj map[string]interface{}, path string) interface{} {
+	var v interface{} = obj
+	for _, field := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[field]
+	}
+	return v
+}
+
+//fieldsOf returns the fields of the object at path prefix which lead to the amount or the currency
+func (t *MoneyType) fieldsOf(prefix string) []string {
+	var fields []string
+	for _, path := range []string{t.Amount, t.Currency} {
+		if prefix != "" {
+			if !strings.HasPrefix(path, prefix+".") {
+				continue
+			}
+			path = path[len(prefix)+1:]
+		}
+		field := strings.SplitN(path, ".", 2)[0]
+		if len(fields) == 0 || fields[0] != field {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+//writeMoney writes m as an object of its money type. Only the fields leading to the amount and the currency
+//are written, java leaves the other fields of the classes to their defaults
+func (b *WriteBuffer) writeMoney(m moneyValue) error {
+	return m.write(newEnumWriter(b), "", m.typ.Class)
+}
+
+func (m moneyValue) write(w *enumWriter, prefix, class string) error {
+	fields := m.typ.fieldsOf(prefix)
+	if err := w.writeInstance(class, fields...); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		path := field
+		if prefix != "" {
+			path = prefix + "." + field
+		}
+		var err error
+		switch path {
+		case m.typ.Amount:
+			if err = w.writeInstance(javaBigDecimal, "value"); err == nil {
+				err = w.write(m.money.Amount)
+			}
+		case m.typ.Currency:
+			err = w.write(string(m.money.Currency))
+		default:
+			err = m.write(w, path, m.typ.Classes[path])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
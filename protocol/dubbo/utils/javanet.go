@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//inetAddressHandle is the class hessian-lite serializes java.net.InetAddress with
+const inetAddressHandle = hessianPackage + "InetAddressHandle"
+
+//urlHandle has the serialized fields of java.net.URL
+type urlHandle struct {
+	Protocol  string
+	Host      string
+	Port      int32
+	File      string
+	Authority string
+	Ref       string
+	HashCode  int32
+}
+
+//toURLHandle converts a url into the fields of java.net.URL, -1 means no port and no cached hash code
+func toURLHandle(u *url.URL) urlHandle {
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		port = -1
+	}
+	file := u.EscapedPath()
+	if u.RawQuery != "" {
+		file += "?" + u.RawQuery
+	}
+	authority := u.Host
+	if u.User != nil {
+		authority = u.User.String() + "@" + authority
+	}
+	return urlHandle{u.Scheme, u.Hostname(), int32(port), file, authority, u.Fragment, -1}
+}
+
+//toURL converts a decoded java.net.URL into a url
+func toURL(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case *url.URL:
+		return val, nil
+	case map[string]interface{}:
+		protocol, _ := val["protocol"].(string)
+		authority, _ := val["authority"].(string)
+		file, _ := val["file"].(string)
+		u, err := url.Parse(protocol + "://" + authority + file)
+		if err != nil {
+			return nil, err
+		}
+		u.Fragment, _ = val["ref"].(string)
+		return u, nil
+	}
+	return nil, &BaseError{"URL is not an object"}
+}
+
+//toInetAddress converts a decoded java.net.InetAddress into an ip, the address is binary in the handle
+//of hessian-lite and an int in the fields of java.net.InetAddress
+func toInetAddress(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case net.IP:
+		return val, nil
+	case map[string]interface{}:
+		switch addr := val["address"].(type) {
+		case []byte:
+			return net.IP(append([]byte(nil), addr...)), nil
+		case string: //gohessian decodes binary into string
+			return net.IP(addr), nil
+		case int32:
+			return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)).To4(), nil
+		}
+	}
+	return nil, &BaseError{"InetAddress has no address"}
+}
+
+//writeInetAddress writes ip as the handle of hessian-lite. gohessian can not write binary fields,
+//so the object is written here, ips nested in other objects are not supported
+func (b *WriteBuffer) writeInetAddress(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	gh := hessian.NewGoHessian(nil, nil)
+	b.WriteBytes([]byte{'C'})
+	for _, v := range []interface{}{inetAddressHandle, int32(2), "hostName", "address"} {
+		if err := gh.ToBytes2(v, b); err != nil {
+			return err
+		}
+	}
+	//the first class definition, and the address is null or binary of 4 or 16 bytes
+	b.WriteBytes([]byte{0x60, 'N'})
+	if len(ip) <= 0x0f {
+		b.WriteBytes([]byte{byte(0x20 + len(ip))})
+	} else {
+		b.WriteBytes([]byte{'B', byte(len(ip) >> 8), byte(len(ip))})
+	}
+	b.WriteBytes(ip)
+	return nil
+}
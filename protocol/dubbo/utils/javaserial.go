@@ -0,0 +1,733 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+//ErrJavaSerialization is returned when a java serialization stream uses a feature JavaSerializer can not read,
+//such as proxy classes or externalizable objects written without block data
+var ErrJavaSerialization = &BaseError{"unsupported java serialization stream"}
+
+//ErrJavaSerializationWrite is returned when a value is written with JavaSerializer, which only reads
+var ErrJavaSerializationWrite = &BaseError{"java serialization can not be written"}
+
+//java serialization stream constants, see java.io.ObjectStreamConstants
+const (
+	javaStreamMagic   = 0xaced
+	javaStreamVersion = 5
+	javaBaseHandle    = 0x7e0000
+
+	tcNull          = 0x70
+	tcReference     = 0x71
+	tcClassDesc     = 0x72
+	tcObject        = 0x73
+	tcString        = 0x74
+	tcArray         = 0x75
+	tcClass         = 0x76
+	tcBlockData     = 0x77
+	tcEndBlockData  = 0x78
+	tcReset         = 0x79
+	tcBlockDataLong = 0x7a
+	tcLongString    = 0x7c
+	tcEnum          = 0x7e
+
+	scWriteMethod    = 0x01
+	scSerializable   = 0x02
+	scExternalizable = 0x04
+	scBlockData      = 0x08
+)
+
+//JavaSerialObject is a struct which holds what JavaSerializer decodes of a java object, its class and the
+//fields of the classes of its hierarchy by name
+type JavaSerialObject struct {
+	Class  string
+	Fields map[string]interface{}
+}
+
+//JavaSerializer is a struct which implements the reading side of BodySerializer with native java serialization,
+//the one of java.io.ObjectOutputStream, or with the compacted java serialization of dubbo when Compacted is set.
+//It is best effort, meant to route on values which can not be fully reconstructed:
+//
+//  - objects are read into JavaSerialObject, strings, enums, boxed primitives and primitive fields into go values
+//  - the entries of java.util.HashMap and its subclasses are read into map[interface{}]interface{}, the other
+//    data written by the writeObject methods of classes is skipped
+//  - compacted streams do not hold the fields of classes other than arrays, so only the class of such an object
+//    is read and the rest of the buffer is consumed, except for the maps above whose fields are known
+//  - the data of externalizable objects is skipped, unless a reader is registered for their class
+//  - proxy classes and externalizable objects written without block data are not supported
+//
+//Dubbo writes a body with one stream, which starts with the stream header. The strings and the marker written
+//before objects, 0 for null and 1 otherwise, are block data, and the objects share the handles of the stream.
+//Objects written without the marker, as ObjectOutputStream.writeObject writes them, are read too
+type JavaSerializer struct {
+	Compacted bool
+}
+
+//stream returns the stream the values of the body in buffer are read from. It is kept on buffer so that the
+//values share its handles and block data, a new stream is started after the stream header, if any, when
+//buffer was read by another serializer since
+func (s JavaSerializer) stream(b *ReadBuffer) *javaStream {
+	r := b.java
+	if r == nil || r.pos != b.rdInd || r.compacted != s.Compacted {
+		r = &javaStream{buf: b.buffer[:b.length], pos: b.rdInd, compacted: s.Compacted}
+		r.skipHeader()
+		b.java = r
+	}
+	return r
+}
+
+//ReadObject is a method to read a java serialized object from buffer
+func (s JavaSerializer) ReadObject(b *ReadBuffer) (interface{}, error) {
+	r := s.stream(b)
+	v, err := r.readValue()
+	if err != nil {
+		return nil, err
+	}
+	b.rdInd = r.pos
+	return v, nil
+}
+
+//ReadUTF is a method to read a string dubbo wrote with writeUTF, null is read as an empty string
+func (s JavaSerializer) ReadUTF(b *ReadBuffer) (string, error) {
+	r := s.stream(b)
+	v, err := r.readDubboUTF(b.maxChars)
+	if err != nil {
+		return "", err
+	}
+	b.rdInd = r.pos
+	return v, nil
+}
+
+//ReadFlag is a method to read a byte dubbo wrote with writeByte
+func (s JavaSerializer) ReadFlag(b *ReadBuffer) (byte, error) {
+	r := s.stream(b)
+	data, err := r.readBlock(1)
+	if err != nil {
+		return 0, err
+	}
+	b.rdInd = r.pos
+	return data[0], nil
+}
+
+//ReadMap is a method to read a map dubbo wrote with writeObject, keys and values which are not strings are
+//formatted
+func (s JavaSerializer) ReadMap(b *ReadBuffer) (map[string]string, error) {
+	v, err := s.ReadObject(b)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	entries, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrNotMap
+	}
+	strMap := make(map[string]string, len(entries))
+	for k, v := range entries {
+		switch val := v.(type) {
+		case string:
+			strMap[fmt.Sprint(k)] = val
+		case nil:
+		default:
+			strMap[fmt.Sprint(k)] = fmt.Sprint(val)
+		}
+	}
+	return strMap, nil
+}
+
+//WriteObject is a method which returns ErrJavaSerializationWrite, values can not be written with java serialization
+func (s JavaSerializer) WriteObject(b *WriteBuffer, v interface{}) error {
+	return ErrJavaSerializationWrite
+}
+
+//javaClassDesc is a class descriptor of a java serialization stream, fields is nil for the classes of
+//compacted streams whose descriptor only holds the name
+type javaClassDesc struct {
+	name   string
+	flags  byte
+	fields []javaField
+	super  *javaClassDesc
+	opaque bool
+}
+
+type javaField struct {
+	typ  byte
+	name string
+}
+
+//javaStream reads a java serialization stream
+type javaStream struct {
+	buf       []byte
+	pos       int
+	compacted bool
+	handles   []interface{}
+	block     []byte //the block data left of the current block
+}
+
+//skipHeader skips the stream header if any, compacted streams only start with the stream version
+func (r *javaStream) skipHeader() {
+	rest := r.buf[r.pos:]
+	if len(rest) >= 4 && binary.BigEndian.Uint16(rest) == javaStreamMagic {
+		r.pos += 4
+	} else if r.compacted && len(rest) > 0 && rest[0] == javaStreamVersion {
+		r.pos++
+	}
+}
+
+//readValue reads the marker dubbo writes before an object in block data, and the object unless the marker is 0.
+//An object is read directly when no block data is next
+func (r *javaStream) readValue() (interface{}, error) {
+	if len(r.block) == 0 {
+		tag, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tag != tcBlockData && tag != tcBlockDataLong {
+			return r.readContent()
+		}
+	}
+	marker, err := r.readBlock(1)
+	if err != nil {
+		return nil, err
+	}
+	switch marker[0] {
+	case 0:
+		return nil, nil
+	case 1:
+		return r.readContent()
+	}
+	return nil, ErrJavaSerialization
+}
+
+//readBlock reads the next n bytes of block data, which may span blocks. ErrJavaSerialization is returned if an
+//object or the end of the data is next
+func (r *javaStream) readBlock(n int) ([]byte, error) {
+	if n > len(r.buf) {
+		return nil, ErrTruncatedValue
+	}
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if len(r.block) == 0 {
+			if err := r.nextBlock(); err != nil {
+				return nil, err
+			}
+		}
+		size := n - len(out)
+		if size > len(r.block) {
+			size = len(r.block)
+		}
+		out = append(out, r.block[:size]...)
+		r.block = r.block[size:]
+	}
+	return out, nil
+}
+
+//nextBlock reads the next block of data, ErrJavaSerialization is returned if an object or the end of the
+//data is next
+func (r *javaStream) nextBlock() error {
+	tag, err := r.peek()
+	if err != nil {
+		return err
+	}
+	lengthSize := 1
+	switch tag {
+	case tcBlockData:
+	case tcBlockDataLong:
+		lengthSize = 4
+	default:
+		return ErrJavaSerialization
+	}
+	r.pos++
+	n, err := r.readUint(lengthSize)
+	if err != nil {
+		return err
+	}
+	r.block, err = r.read(int(n))
+	return err
+}
+
+//readBlockUTF reads a string written with writeUTF from the block data, in modified utf-8 which is read as utf-8
+func (r *javaStream) readBlockUTF() (string, error) {
+	data, err := r.readBlock(2)
+	if err != nil {
+		return "", err
+	}
+	data, err = r.readBlock(int(binary.BigEndian.Uint16(data)))
+	return string(data), err
+}
+
+//readDubboUTF reads a string dubbo wrote with writeUTF, its length in characters, -1 for null, followed by the
+//string java writes with writeUTF. ErrStringTooLong is returned when the length exceeds maxChars, 0 means no limit
+func (r *javaStream) readDubboUTF(maxChars int) (string, error) {
+	data, err := r.readBlock(4)
+	if err != nil {
+		return "", err
+	}
+	n := int32(binary.BigEndian.Uint32(data))
+	if n < 0 {
+		return "", nil
+	}
+	if maxChars > 0 && int(n) > maxChars {
+		return "", ErrStringTooLong
+	}
+	return r.readBlockUTF()
+}
+
+func (r *javaStream) peek() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, ErrTruncatedValue
+	}
+	return r.buf[r.pos], nil
+}
+
+//read returns the next n bytes of stream
+func (r *javaStream) read(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.buf) {
+		return nil, ErrTruncatedValue
+	}
+	r.pos += n
+	return r.buf[r.pos-n : r.pos], nil
+}
+
+func (r *javaStream) readByte() (byte, error) {
+	data, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+func (r *javaStream) readUint(n int) (uint64, error) {
+	data, err := r.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range data {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+//readUTF reads a string prefixed by its length, java writes it in modified utf-8 which is read as utf-8
+func (r *javaStream) readUTF(lengthSize int) (string, error) {
+	n, err := r.readUint(lengthSize)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(r.buf)) {
+		return "", ErrTruncatedValue
+	}
+	data, err := r.read(int(n))
+	return string(data), err
+}
+
+//assign gives the next handle to v and returns its index
+func (r *javaStream) assign(v interface{}) int {
+	r.handles = append(r.handles, v)
+	return len(r.handles) - 1
+}
+
+func (r *javaStream) readHandle() (interface{}, error) {
+	h, err := r.readUint(4)
+	if err != nil {
+		return nil, err
+	}
+	idx := int(h) - javaBaseHandle
+	if idx < 0 || idx >= len(r.handles) {
+		return nil, ErrJavaSerialization
+	}
+	return r.handles[idx], nil
+}
+
+//readContent reads the next object, string, array, enum or class of stream
+func (r *javaStream) readContent() (interface{}, error) {
+	tag, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tcNull:
+		return nil, nil
+	case tcReference:
+		return r.readHandle()
+	case tcString, tcLongString:
+		return r.readString(tag)
+	case tcObject:
+		return r.readObject()
+	case tcArray:
+		return r.readArray()
+	case tcEnum:
+		return r.readEnum()
+	case tcClass:
+		desc, err := r.readClassDesc()
+		if err != nil || desc == nil {
+			return nil, err
+		}
+		r.assign(desc.name)
+		return desc.name, nil
+	case tcReset:
+		r.handles = nil
+		return r.readContent()
+	}
+	return nil, ErrJavaSerialization
+}
+
+func (r *javaStream) readString(tag byte) (string, error) {
+	lengthSize := 2
+	if tag == tcLongString {
+		lengthSize = 8
+	}
+	s, err := r.readUTF(lengthSize)
+	if err == nil {
+		r.assign(s)
+	}
+	return s, err
+}
+
+//readClassDesc reads a class descriptor, nil is the null descriptor which ends a class hierarchy
+func (r *javaStream) readClassDesc() (*javaClassDesc, error) {
+	tag, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tcNull:
+		return nil, nil
+	case tcReference:
+		h, err := r.readHandle()
+		desc, ok := h.(*javaClassDesc)
+		if err == nil && !ok {
+			err = ErrJavaSerialization
+		}
+		return desc, err
+	case tcClassDesc:
+		desc := &javaClassDesc{}
+		r.assign(desc)
+		if err := r.readClassInfo(desc); err != nil {
+			return nil, err
+		}
+		if err := r.skipAnnotation(); err != nil {
+			return nil, err
+		}
+		desc.super, err = r.readClassDesc()
+		return desc, err
+	}
+	return nil, ErrJavaSerialization
+}
+
+//readClassInfo reads the name, flags and fields of a class descriptor. Compacted streams only hold the
+//name of the classes other than arrays
+func (r *javaStream) readClassInfo(desc *javaClassDesc) error {
+	if r.compacted {
+		kind, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if kind == 1 {
+			desc.name, err = r.readUTF(2)
+			if known, ok := javaCompactedClasses[desc.name]; ok {
+				desc.flags, desc.fields = known.flags, known.fields
+			} else {
+				desc.opaque = true
+			}
+			return err
+		}
+	}
+	var err error
+	if desc.name, err = r.readUTF(2); err != nil {
+		return err
+	}
+	if _, err := r.read(8); err != nil { //serialVersionUID
+		return err
+	}
+	if desc.flags, err = r.readByte(); err != nil {
+		return err
+	}
+	count, err := r.readUint(2)
+	if err != nil {
+		return err
+	}
+	desc.fields = make([]javaField, count)
+	for i := range desc.fields {
+		if err := r.readField(&desc.fields[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *javaStream) readField(field *javaField) error {
+	var err error
+	if field.typ, err = r.readByte(); err != nil {
+		return err
+	}
+	if field.name, err = r.readUTF(2); err != nil {
+		return err
+	}
+	if field.typ == '[' || field.typ == 'L' {
+		_, err = r.readContent() //class name of the field
+	}
+	return err
+}
+
+//skipAnnotation skips the block data and objects written by the writeObject methods up to the end marker
+func (r *javaStream) skipAnnotation() error {
+	r.block = nil
+	for {
+		tag, err := r.peek()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case tcEndBlockData:
+			r.pos++
+			return nil
+		case tcBlockData, tcBlockDataLong:
+			r.pos++
+			err = r.skipBlockData(tag)
+		default:
+			_, err = r.readContent()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *javaStream) skipBlockData(tag byte) error {
+	lengthSize := 1
+	if tag == tcBlockDataLong {
+		lengthSize = 4
+	}
+	n, err := r.readUint(lengthSize)
+	if err != nil {
+		return err
+	}
+	_, err = r.read(int(n))
+	return err
+}
+
+//readObject reads the class descriptor and the data of an object
+func (r *javaStream) readObject() (interface{}, error) {
+	desc, err := r.readClassDesc()
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, ErrJavaSerialization
+	}
+	obj := &JavaSerialObject{Class: desc.name, Fields: make(map[string]interface{})}
+	handle := r.assign(obj)
+	if reader, ok := externalReaders[desc.name]; ok && desc.flags&scBlockData != 0 {
+		v, err := r.readExternal(reader)
+		r.handles[handle] = v
+		return v, err
+	}
+	var hierarchy []*javaClassDesc
+	for d := desc; d != nil; d = d.super {
+		hierarchy = append([]*javaClassDesc{d}, hierarchy...)
+	}
+	var entries map[interface{}]interface{}
+	for _, d := range hierarchy {
+		if d.opaque {
+			r.pos = len(r.buf)
+			return obj, nil
+		}
+		m, err := r.readClassData(d, obj.Fields)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			entries = m
+		}
+	}
+	if entries != nil {
+		r.handles[handle] = entries
+		return entries, nil
+	}
+	if v, ok := obj.Fields["value"]; ok && len(obj.Fields) == 1 && isJavaBoxed(obj.Class) {
+		r.handles[handle] = v
+		return v, nil
+	}
+	return obj, nil
+}
+
+//isJavaBoxed reports whether class is one of the boxed primitives of java.lang
+func isJavaBoxed(class string) bool {
+	switch class {
+	case "java.lang.Boolean", "java.lang.Byte", "java.lang.Character", "java.lang.Short",
+		"java.lang.Integer", "java.lang.Long", "java.lang.Float", "java.lang.Double":
+		return true
+	}
+	return false
+}
+
+//readClassData reads the fields one class of the hierarchy of an object wrote and the data of its writeObject
+//method, the entries of java.util.HashMap are returned
+func (r *javaStream) readClassData(desc *javaClassDesc, fields map[string]interface{}) (map[interface{}]interface{}, error) {
+	if desc.flags&scExternalizable != 0 {
+		if desc.flags&scBlockData == 0 {
+			return nil, ErrJavaSerialization
+		}
+		return nil, r.skipAnnotation()
+	}
+	if desc.flags&scSerializable == 0 {
+		return nil, nil
+	}
+	for _, field := range desc.fields {
+		v, err := r.readFieldValue(field.typ)
+		if err != nil {
+			return nil, err
+		}
+		fields[field.name] = v
+	}
+	if desc.flags&scWriteMethod == 0 {
+		return nil, nil
+	}
+	if desc.name == javaHashMap {
+		return r.readMapEntries()
+	}
+	return nil, r.skipAnnotation()
+}
+
+//javaHashMap is the class whose writeObject method writes the entries of java.util.HashMap and its subclasses
+const javaHashMap = "java.util.HashMap"
+
+//javaCompactedClasses holds the descriptors of the maps whose class compacted streams only name, they are
+//known so that the entries of maps can be read from compacted streams
+var javaCompactedClasses = map[string]javaClassDesc{
+	javaHashMap: {flags: scSerializable | scWriteMethod,
+		fields: []javaField{{'F', "loadFactor"}, {'I', "threshold"}}},
+	"java.util.LinkedHashMap": {flags: scSerializable, fields: []javaField{{'Z', "accessOrder"}}},
+}
+
+//readMapEntries reads what the writeObject method of java.util.HashMap writes, the capacity and the size of the
+//map in block data, the keys and values of the entries, then the end marker
+func (r *javaStream) readMapEntries() (map[interface{}]interface{}, error) {
+	data, err := r.readBlock(8)
+	if err != nil {
+		return nil, err
+	}
+	size := int(int32(binary.BigEndian.Uint32(data[4:])))
+	if size < 0 || size > len(r.buf) {
+		return nil, ErrJavaSerialization
+	}
+	entries := make(map[interface{}]interface{}, size)
+	for i := 0; i < size; i++ {
+		k, err := r.readContent()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, ErrJavaSerialization
+		}
+		if entries[k], err = r.readContent(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, r.skipAnnotation()
+}
+
+//readFieldValue reads a value of the field type code typ, such as I for int
+func (r *javaStream) readFieldValue(typ byte) (interface{}, error) {
+	size, ok := javaPrimitiveSizes[typ]
+	if !ok {
+		return r.readContent()
+	}
+	v, err := r.readUint(size)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case 'B':
+		return int8(v), nil
+	case 'C':
+		return rune(v), nil
+	case 'S':
+		return int16(v), nil
+	case 'I':
+		return int32(v), nil
+	case 'J':
+		return int64(v), nil
+	case 'F':
+		return math.Float32frombits(uint32(v)), nil
+	case 'D':
+		return math.Float64frombits(v), nil
+	}
+	return v != 0, nil
+}
+
+//javaPrimitiveSizes holds the sizes of the primitive field type codes
+var javaPrimitiveSizes = map[byte]int{'B': 1, 'C': 2, 'S': 2, 'I': 4, 'J': 8, 'F': 4, 'D': 8, 'Z': 1}
+
+//readArray reads an array, byte arrays are read into byte slices and other arrays into slices of interfaces
+func (r *javaStream) readArray() (interface{}, error) {
+	desc, err := r.readClassDesc()
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil || len(desc.name) < 2 {
+		return nil, ErrJavaSerialization
+	}
+	handle := r.assign(nil)
+	size, err := r.readUint(4)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(len(r.buf)) {
+		return nil, ErrTruncatedValue
+	}
+	if desc.name == "[B" {
+		data, err := r.read(int(size))
+		arr := append([]byte(nil), data...)
+		r.handles[handle] = arr
+		return arr, err
+	}
+	arr := make([]interface{}, size)
+	for i := range arr {
+		if arr[i], err = r.readFieldValue(desc.name[1]); err != nil {
+			return nil, err
+		}
+	}
+	r.handles[handle] = arr
+	return arr, nil
+}
+
+//readEnum reads an enum constant
+func (r *javaStream) readEnum() (interface{}, error) {
+	desc, err := r.readClassDesc()
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, ErrJavaSerialization
+	}
+	handle := r.assign(nil)
+	name, err := r.readContent()
+	if err != nil {
+		return nil, err
+	}
+	s, _ := name.(string)
+	e := JavaEnum{Class: desc.name, Name: s}
+	r.handles[handle] = e
+	return e, nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//javaPoint is what the java serialization of dubbo writes for an argument, an instance of
+//class com.demo.Point implements Serializable { int x; int y; String label; }: the stream header, the marker 1
+//in a block of data, then the object
+var javaPoint = []byte("\xac\xed\x00\x05" + "\x77\x01\x01" +
+	"\x73\x72\x00\x0ecom.demo.Point\x00\x00\x00\x00\x00\x00\x00\x01\x02\x00\x03" +
+	"I\x00\x01x" + "I\x00\x01y" + "L\x00\x05label\x74\x00\x12Ljava/lang/String;" + "\x78\x70" +
+	"\x00\x00\x00\x01" + "\x00\x00\x00\x02" + "\x74\x00\x02p1")
+
+func TestJavaSerializer_ReadObject(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(append(append([]byte(nil), javaPoint...), 'N'))
+	obj, err := JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, &JavaSerialObject{
+		Class:  "com.demo.Point",
+		Fields: map[string]interface{}{"x": int32(1), "y": int32(2), "label": "p1"},
+	}, obj)
+	next, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	rbf.SetBuffer([]byte("\xac\xed\x00\x05\x77\x01\x00"))
+	obj, err = JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+
+	rbf.SetBuffer(javaPoint[:len(javaPoint)-1])
+	_, err = JavaSerializer{}.ReadObject(&rbf)
+	assert.Equal(t, ErrTruncatedValue, err)
+
+	assert.Equal(t, ErrJavaSerializationWrite, JavaSerializer{}.WriteObject(nil, "x"))
+}
+
+//javaSecondPoint is what follows javaPoint when a second point is written to the same stream, the class
+//descriptor and the label refer to the handles of the first point
+var javaSecondPoint = []byte("\x77\x01\x01" + "\x73\x71\x00\x7e\x00\x00" +
+	"\x00\x00\x00\x03" + "\x00\x00\x00\x04" + "\x71\x00\x7e\x00\x03")
+
+func TestJavaSerializer_SharedHandles(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(append(append([]byte(nil), javaPoint...), javaSecondPoint...))
+	_, err := JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	obj, err := JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, &JavaSerialObject{
+		Class:  "com.demo.Point",
+		Fields: map[string]interface{}{"x": int32(3), "y": int32(4), "label": "p1"},
+	}, obj)
+	assert.Equal(t, len(rbf.Bytes()), rbf.Consumed())
+
+	rbf.SetBuffer(javaSecondPoint)
+	_, err = JavaSerializer{}.ReadObject(&rbf)
+	assert.Equal(t, ErrJavaSerialization, err)
+}
+
+//javaAttachments is what java.io.ObjectOutputStream writes for a java.util.HashMap holding path=com.demo.Hello,
+//the fields of the map, then the capacity, the size and the entries written by its writeObject method
+var javaAttachments = []byte("\x73\x72\x00\x11java.util.HashMap\x05\x07\xda\xc1\xc3\x16\x60\xd1\x03\x00\x02" +
+	"F\x00\x0aloadFactor" + "I\x00\x09threshold" + "\x78\x70" +
+	"\x3f\x40\x00\x00" + "\x00\x00\x00\x0c" + "\x77\x08\x00\x00\x00\x10\x00\x00\x00\x01" +
+	"\x74\x00\x04path" + "\x74\x00\x0ecom.demo.Hello" + "\x78")
+
+func TestJavaSerializer_Body(t *testing.T) {
+	//writeUTF("2.0.2"), writeByte(4) and the marker of a map, then writeUTF(null) and the marker of null
+	body := []byte("\xac\xed\x00\x05" + "\x77\x0d\x00\x00\x00\x05\x00\x052.0.2\x04\x01")
+	body = append(body, javaAttachments...)
+	body = append(body, "\x77\x05\xff\xff\xff\xff\x00"...)
+	var rbf ReadBuffer
+	rbf.SetBuffer(body)
+	s := JavaSerializer{}
+	version, err := s.ReadUTF(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.2", version)
+	flag, err := s.ReadFlag(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(4), flag)
+	attachments, err := s.ReadMap(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"path": "com.demo.Hello"}, attachments)
+	null, err := s.ReadUTF(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, "", null)
+	obj, err := s.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+	assert.Equal(t, len(body), rbf.Consumed())
+	_, err = s.ReadFlag(&rbf)
+	assert.Equal(t, ErrTruncatedValue, err)
+
+	rbf.SetBuffer(body)
+	rbf.SetMaxStringLength(4)
+	_, err = s.ReadUTF(&rbf)
+	assert.Equal(t, ErrStringTooLong, err)
+
+	//compacted streams only name the class of the map
+	compacted := []byte("\x05\x77\x01\x01\x73\x72\x01\x00\x11java.util.HashMap\x78\x70")
+	compacted = append(compacted, javaAttachments[bytes.Index(javaAttachments, []byte("\x78\x70"))+2:]...)
+	rbf.SetBuffer(compacted)
+	attachments, err = JavaSerializer{Compacted: true}.ReadMap(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"path": "com.demo.Hello"}, attachments)
+}
+
+func TestJavaSerializer_Compacted(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer([]byte("\x05\x77\x01\x01\x73\x72\x01\x00\x0ecom.demo.Point\x78\x70" +
+		"\x00\x00\x00\x01\x00\x00\x00\x02\x74\x00\x02p1"))
+	obj, err := JavaSerializer{Compacted: true}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, "com.demo.Point", obj.(*JavaSerialObject).Class)
+	assert.Empty(t, obj.(*JavaSerialObject).Fields)
+}
+
+//javaExternalPoint is what java.io.ObjectOutputStream writes for an instance of class com.demo.ExternalPoint
+//implements Externalizable, whose writeExternal writes the ints x and y, then the object label
+var javaExternalPoint = []byte("\xac\xed\x00\x05" +
+	"\x73\x72\x00\x16com.demo.ExternalPoint\x00\x00\x00\x00\x00\x00\x00\x01\x0c\x00\x00\x78\x70" +
+	"\x77\x08\x00\x00\x00\x03\x00\x00\x00\x04" + "\x74\x00\x02p3" + "\x78")
+
+type externalPoint struct {
+	X, Y  int32
+	Label string
+}
+
+func TestJavaSerializer_ExternalReader(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(javaExternalPoint)
+	obj, err := JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, &JavaSerialObject{Class: "com.demo.ExternalPoint", Fields: map[string]interface{}{}}, obj)
+
+	RegisterExternalReader("com.demo.ExternalPoint", func(in *ObjectInput) (interface{}, error) {
+		var p externalPoint
+		var err error
+		if p.X, err = in.ReadInt(); err != nil {
+			return nil, err
+		}
+		if p.Y, err = in.ReadInt(); err != nil {
+			return nil, err
+		}
+		label, err := in.ReadObject()
+		p.Label, _ = label.(string)
+		return p, err
+	})
+	defer delete(externalReaders, "com.demo.ExternalPoint")
+	rbf.SetBuffer(append(append([]byte(nil), javaExternalPoint...), 'N'))
+	obj, err = JavaSerializer{}.ReadObject(&rbf)
+	assert.NoError(t, err)
+	assert.Equal(t, externalPoint{3, 4, "p3"}, obj)
+	next, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/go-chassis/gohessian"
+)
+
+//orderedSets are the java sets whose iteration order is kept, hessian writes sets as typed lists of their class
+var orderedSets = map[string]bool{
+	"java.util.TreeSet":       true,
+	"java.util.LinkedHashSet": true,
+}
+
+//OrderedSet is the go form of the java sets iterated in an order, such as java.util.TreeSet and
+//java.util.LinkedHashSet. Elements keep the order they were written in, and the set is encoded as its class
+type OrderedSet struct {
+	Class    string
+	Elements []interface{}
+}
+
+//orderedSetClass returns the class of the next value of buffer if it is an ordered set
+func (b *ReadBuffer) orderedSetClass() (string, bool) {
+	w := &classWalker{scanner: scanner{buf: b.buffer[b.rdInd:b.length]}}
+	if tag, err := w.next(); err != nil || !isTypedList(tag) {
+		return "", false
+	}
+	class, err := w.readType()
+	return class, err == nil && orderedSets[class]
+}
+
+//readOrderedSet reads a typed list of an ordered set class into an ordered set
+func (b *ReadBuffer) readOrderedSet(class string) (interface{}, error) {
+	obj, err := b.readTypedList()
+	if err != nil {
+		return nil, err
+	}
+	elements, _ := obj.([]interface{})
+	return OrderedSet{Class: class, Elements: elements}, nil
+}
+
+//writeOrderedSet writes s as a typed list of its class, the elements are written in order by gohessian
+func (b *WriteBuffer) writeOrderedSet(s OrderedSet) error {
+	var elements WriteBuffer
+	elements.Init(0)
+	if err := elements.WriteObject(s.Elements); err != nil {
+		return err
+	}
+	b.WriteBytes([]byte{hessian.BC_LIST_FIXED})
+	if err := newEnumWriter(b).write(s.Class); err != nil {
+		return err
+	}
+	//gohessian starts the list with the untyped tag, followed by the length and the elements
+	b.WriteBytes(elements.GetValidData()[1:])
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-chassis/gohessian"
+)
+
+//java8HandlePackage is the package of the handles which hessian-lite serializes java.time values with
+const java8HandlePackage = hessianPackage + "java8."
+
+type localDateHandle struct {
+	Year  int32
+	Month int32
+	Day   int32
+}
+
+type localTimeHandle struct {
+	Hour   int32
+	Minute int32
+	Second int32
+	Nano   int32
+}
+
+type localDateTimeHandle struct {
+	Date localDateHandle
+	Time localTimeHandle
+}
+
+type zoneOffsetHandle struct {
+	Seconds int32
+}
+
+type zonedDateTimeHandle struct {
+	DateTime localDateTimeHandle
+	Offset   zoneOffsetHandle
+	ZoneId   string
+}
+
+type offsetDateTimeHandle struct {
+	DateTime localDateTimeHandle
+	Offset   zoneOffsetHandle
+}
+
+type durationHandle struct {
+	Seconds int64
+	Nanos   int32
+}
+
+//instantHandle has the seconds and nanos of java.time.Instant, gohessian can not write the long seconds,
+//so the handle is written by writeInstant
+type instantHandle struct {
+	Seconds int64
+	Nanos   int32
+}
+
+type periodHandle struct {
+	Years  int32
+	Months int32
+	Days   int32
+}
+
+//Period is the go form of java.time.Period, an amount of calendar time whose length depends on the date
+//it is added to, unlike a time.Duration
+type Period struct {
+	Years  int32
+	Months int32
+	Days   int32
+}
+
+//AddTo is a method which returns t moved by period in the calendar of t
+func (p Period) AddTo(t time.Time) time.Time {
+	return t.AddDate(int(p.Years), int(p.Months), int(p.Days))
+}
+
+//toZonedDateTimeHandle converts a time into the handle of java.time.ZonedDateTime.
+//gohessian can not write java.util.Date, so calendars are written in this form too
+func toZonedDateTimeHandle(t time.Time) zonedDateTimeHandle {
+	_, offset := t.Zone()
+	return zonedDateTimeHandle{
+		DateTime: localDateTimeHandle{
+			Date: localDateHandle{int32(t.Year()), int32(t.Month()), int32(t.Day())},
+			Time: localTimeHandle{int32(t.Hour()), int32(t.Minute()), int32(t.Second()), int32(t.Nanosecond())},
+		},
+		Offset: zoneOffsetHandle{int32(offset)},
+		ZoneId: t.Location().String(),
+	}
+}
+
+//toTime converts a decoded java.util.Calendar or java.time.ZonedDateTime into a time with location
+func toTime(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case map[string]interface{}:
+		dateTime, _ := val["dateTime"].(map[string]interface{})
+		date, _ := dateTime["date"].(map[string]interface{})
+		clock, _ := dateTime["time"].(map[string]interface{})
+		if date == nil || clock == nil {
+			return nil, &BaseError{"date time has no date or time"}
+		}
+		offset, _ := val["offset"].(map[string]interface{})
+		zoneID, _ := val["zoneId"].(string)
+		loc := zoneLocation(zoneID, int(intField(offset, "seconds")))
+		return time.Date(int(intField(date, "year")), time.Month(intField(date, "month")), int(intField(date, "day")),
+			int(intField(clock, "hour")), int(intField(clock, "minute")), int(intField(clock, "second")),
+			int(intField(clock, "nano")), loc), nil
+	}
+	return nil, &BaseError{"date time is not an object"}
+}
+
+//toOffsetDateTimeHandle converts a time into the handle of java.time.OffsetDateTime, which keeps the offset of
+//the time but not its zone
+func toOffsetDateTimeHandle(v interface{}) interface{} {
+	t, ok := v.(time.Time)
+	if !ok {
+		return v
+	}
+	zoned := toZonedDateTimeHandle(t)
+	return offsetDateTimeHandle{zoned.DateTime, zoned.Offset}
+}
+
+//toOffsetDateTime converts a decoded java.time.OffsetDateTime into a time whose location is the fixed zone of
+//its offset, such as +05:30, so the offset is kept rather than normalized to UTC
+func toOffsetDateTime(v interface{}) (interface{}, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return nil, err
+	}
+	tm := t.(time.Time)
+	_, offset := tm.Zone()
+	return tm.In(offsetZone(offset)), nil
+}
+
+//offsetZone returns the fixed zone of offset seconds named like the ids of java.time.ZoneOffset
+func offsetZone(offset int) *time.Location {
+	if offset == 0 {
+		return time.FixedZone("Z", 0)
+	}
+	sign, abs := '+', offset
+	if offset < 0 {
+		sign, abs = '-', -offset
+	}
+	name := fmt.Sprintf("%c%02d:%02d", sign, abs/3600, abs/60%60)
+	if abs%60 != 0 {
+		name += fmt.Sprintf(":%02d", abs%60)
+	}
+	return time.FixedZone(name, offset)
+}
+
+//zoneLocation loads the location of zone id, falling back to a fixed zone of offset
+func zoneLocation(zoneID string, offset int) *time.Location {
+	if zoneID != "" {
+		if loc, err := time.LoadLocation(zoneID); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone(zoneID, offset)
+}
+
+func intField(fields map[string]interface{}, name string) int64 {
+	switch v := fields[name].(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
+
+//toDurationHandle converts a duration into the handle of java.time.Duration, whose nanos are never negative
+func toDurationHandle(d time.Duration) durationHandle {
+	seconds, nanos := int64(d/time.Second), int32(d%time.Second)
+	if nanos < 0 {
+		seconds--
+		nanos += int32(time.Second)
+	}
+	return durationHandle{seconds, nanos}
+}
+
+//toDuration converts a decoded java.time.Duration into a duration
+func toDuration(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Duration:
+		return val, nil
+	case map[string]interface{}:
+		return time.Duration(intField(val, "seconds"))*time.Second + time.Duration(intField(val, "nanos")), nil
+	}
+	return nil, &BaseError{"Duration is not an object"}
+}
+
+//toInstantHandle converts a time into the handle of java.time.Instant, keeping its nanoseconds
+func toInstantHandle(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return instantHandle{t.Unix(), int32(t.Nanosecond())}
+	}
+	return v
+}
+
+//toInstant converts a decoded java.time.Instant into a time in UTC with the nanoseconds of the instant
+func toInstant(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case map[string]interface{}:
+		return time.Unix(intField(val, "seconds"), intField(val, "nanos")).UTC(), nil
+	}
+	return nil, &BaseError{"Instant is not an object"}
+}
+
+//writeInstant writes h as the handle of java.time.Instant, with the seconds in the int form of longs when
+//they fit in it like java
+func (b *WriteBuffer) writeInstant(h instantHandle) error {
+	w := newEnumWriter(b)
+	if err := w.writeInstance(javaClassName("instantHandle"), "seconds", "nanos"); err != nil {
+		return err
+	}
+	if h.Seconds >= math.MinInt32 && h.Seconds <= math.MaxInt32 {
+		buf := []byte{hessian.BC_LONG_INT, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(buf[1:], uint32(h.Seconds))
+		b.WriteBytes(buf)
+	} else {
+		buf := make([]byte, 9)
+		buf[0] = hessian.BC_LONG
+		binary.BigEndian.PutUint64(buf[1:], uint64(h.Seconds))
+		b.WriteBytes(buf)
+	}
+	return w.write(h.Nanos)
+}
+
+//toPeriod converts a decoded java.time.Period into a period
+func toPeriod(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case Period:
+		return val, nil
+	case map[string]interface{}:
+		return Period{int32(intField(val, "years")), int32(intField(val, "months")), int32(intField(val, "days"))}, nil
+	}
+	return nil, &BaseError{"Period is not an object"}
+}
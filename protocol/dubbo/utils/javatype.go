@@ -0,0 +1,546 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Locale is the go form of java.util.Locale, a language tag such as "zh-CN"
+type Locale string
+
+//Currency is the go form of java.util.Currency, an ISO 4217 code such as "CNY"
+type Currency string
+
+//Pattern is the go form of java.util.regex.Pattern, the regular expression and the flags it is compiled with
+type Pattern struct {
+	Pattern string
+	Flags   int32
+}
+
+//JavaEnum is the go form of a java enum constant, the enum class and the name of the constant
+type JavaEnum struct {
+	Class string
+	Name  string
+}
+
+//hessianPackage is the package of hessian-lite serializers and handles
+const hessianPackage = "com.alibaba.com.caucho.hessian.io."
+
+//javaClassNames maps the go handle types to their java class names
+var javaClassNames = map[string]string{
+	"localDateHandle":      java8HandlePackage + "LocalDateHandle",
+	"localTimeHandle":      java8HandlePackage + "LocalTimeHandle",
+	"localDateTimeHandle":  java8HandlePackage + "LocalDateTimeHandle",
+	"zoneOffsetHandle":     java8HandlePackage + "ZoneOffsetHandle",
+	"zonedDateTimeHandle":  java8HandlePackage + "ZonedDateTimeHandle",
+	"offsetDateTimeHandle": java8HandlePackage + "OffsetDateTimeHandle",
+	"durationHandle":       java8HandlePackage + "DurationHandle",
+	"periodHandle":         java8HandlePackage + "PeriodHandle",
+	"instantHandle":        java8HandlePackage + "InstantHandle",
+	"localeHandle":         hessianPackage + "LocaleHandle",
+	"currencyHandle":       "java.util.Currency",
+	"patternHandle":        "java.util.regex.Pattern",
+	"urlHandle":            "java.net.URL",
+	"atomicIntegerHandle":  atomicPackage + "AtomicInteger",
+	"atomicLongHandle":     atomicPackage + "AtomicLong",
+	"longAdderHandle":      atomicPackage + "LongAdder$SerializationProxy",
+	"doubleAdderHandle":    atomicPackage + "DoubleAdder$SerializationProxy",
+}
+
+type localeHandle struct {
+	Value string
+}
+
+type currencyHandle struct {
+	Value string
+}
+
+type patternHandle struct {
+	Pattern string
+	Flags   int32
+}
+
+//javaTypesMtx guards TypMap, javaClassNames, goFieldNames and javaFieldNames. The registrations replace the
+//maps by modified copies, so that the maps read under the lock can still be used by a decode once it is released
+var javaTypesMtx sync.RWMutex
+
+//newJavaClassNames returns a copy of javaClassNames, the hessian encoder adds the names of other types to it
+func newJavaClassNames() map[string]string {
+	javaTypesMtx.RLock()
+	defer javaTypesMtx.RUnlock()
+	return copyNames(javaClassNames)
+}
+
+//javaClassName returns the java class name of a go type
+func javaClassName(goName string) string {
+	javaTypesMtx.RLock()
+	defer javaTypesMtx.RUnlock()
+	return javaClassNames[goName]
+}
+
+//registeredTypes returns the go structs registered for java classes, the map must not be modified
+func registeredTypes() map[string]reflect.Type {
+	javaTypesMtx.RLock()
+	defer javaTypesMtx.RUnlock()
+	return TypMap
+}
+
+//copyNames returns a copy of the class names
+func copyNames(names map[string]string) map[string]string {
+	out := make(map[string]string, len(names))
+	for k, v := range names {
+		out[k] = v
+	}
+	return out
+}
+
+//copyTypes returns a copy of the registered types
+func copyTypes(types map[string]reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, len(types))
+	for k, v := range types {
+		out[k] = v
+	}
+	return out
+}
+
+//javaTypeConverters convert decoded values into the go type matching a java type descriptor
+var javaTypeConverters = map[string]func(interface{}) (interface{}, error){
+	JavaBoolArray:      toBoolArray,
+	JavaBooleanArray:   toBoxedBoolArray,
+	JavaCalendar:       toTime,
+	JavaZonedDateTime:  toTime,
+	JavaOffsetDateTime: toOffsetDateTime,
+	JavaLocale:         toLocale,
+	JavaCurrency:       toCurrency,
+	JavaStream:         toStreamList,
+	JavaPattern:        toPattern,
+	JavaInetAddress:    toInetAddress,
+	JavaURL:            toURL,
+	JavaEnumSet:        toEnumSet,
+	JavaEnumMap:        toEnumMap,
+	JavaAtomicInteger:  toAtomicInteger,
+	JavaAtomicLong:     toAtomicLong,
+	JavaLongAdder:      toLongAdder,
+	JavaDoubleAdder:    toDoubleAdder,
+	JavaDuration:       toDuration,
+	JavaPeriod:         toPeriod,
+	JavaInstant:        toInstant,
+	JavaBitSet:         toBitSet,
+}
+
+//javaValueConverters convert go values into the form of java types which have no go type of their own
+var javaValueConverters = map[string]func(interface{}) interface{}{
+	JavaAtomicInteger:  toAtomicIntegerHandle,
+	JavaAtomicLong:     toAtomicLongHandle,
+	JavaLongAdder:      toLongAdderHandle,
+	JavaDoubleAdder:    toDoubleAdderHandle,
+	JavaOffsetDateTime: toOffsetDateTimeHandle,
+	JavaInstant:        toInstantHandle,
+}
+
+//RegisterJavaType is a function which maps a java class, such as a POJO or a record, to the go struct of v.
+//Objects of the class are decoded into pointers to the struct, whose fields match the java fields or record
+//components by name, and the struct is encoded with the class name. Types should be registered during init
+func RegisterJavaType(javaClass string, v interface{}) {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	javaTypesMtx.Lock()
+	registerJavaType(javaClass, typ)
+	javaTypesMtx.Unlock()
+}
+
+//registerJavaType maps javaClass to typ, javaTypesMtx must be locked
+func registerJavaType(javaClass string, typ reflect.Type) {
+	types := copyTypes(TypMap)
+	types[javaClass] = typ
+	names := copyNames(javaClassNames)
+	names[typ.Name()] = javaClass
+	TypMap, javaClassNames = types, names
+}
+
+//UnregisterJavaType is a function which removes the mapping of a java class registered with RegisterJavaType
+//or RegisterJavaTypeFields, objects of the class are decoded into maps again
+func UnregisterJavaType(javaClass string) {
+	javaTypesMtx.Lock()
+	defer javaTypesMtx.Unlock()
+	types := copyTypes(TypMap)
+	names := copyNames(javaClassNames)
+	if typ, ok := types[javaClass]; ok {
+		delete(names, typ.Name())
+	}
+	delete(types, javaClass)
+	goFields, javaFields := copyFieldNames(goFieldNames), copyFieldNames(javaFieldNames)
+	delete(goFields, javaClass)
+	delete(javaFields, javaClass)
+	TypMap, javaClassNames, goFieldNames, javaFieldNames = types, names, goFields, javaFields
+}
+
+//ConvertByJavaType is a function which converts a decoded value into the go type of java type descriptor.
+//Objects of registered classes keep the go type of the class they were written with, which may be a
+//subclass of javaType when it is declared as an abstract class or an interface
+func ConvertByJavaType(javaType string, v interface{}) (interface{}, error) {
+	if rv, ok := v.(reflect.Value); ok && rv.Kind() == reflect.Ptr { //registered java classes
+		return rv.Interface(), nil
+	}
+	if convert, ok := javaTypeConverters[javaType]; ok && v != nil {
+		return convert(v)
+	}
+	if lst, ok := v.([]interface{}); ok && strings.HasPrefix(javaType, "[L") {
+		return toObjectArray(javaType, lst), nil
+	}
+	return v, nil
+}
+
+//toObjectArray converts a decoded array of objects into a slice of pointers to the go struct registered for
+//the class of array, or into a slice of maps if the class is not registered. Null elements are kept as nil,
+//arrays holding other values, such as objects of subclasses, are returned as slices of interfaces
+func toObjectArray(javaType string, lst []interface{}) interface{} {
+	class := strings.Replace(strings.TrimSuffix(javaType[2:], ";"), "/", ".", -1)
+	var out reflect.Value
+	if typ, ok := registeredTypes()[class]; ok {
+		out = reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(typ)), len(lst), len(lst))
+	} else {
+		out = reflect.ValueOf(make([]map[string]interface{}, len(lst)))
+	}
+	elemType := out.Type().Elem()
+	found, mixed := false, false
+	for i, item := range lst {
+		if rv, ok := item.(reflect.Value); ok {
+			item = rv.Interface()
+			lst[i] = item
+		}
+		if item == nil {
+			continue
+		}
+		if reflect.TypeOf(item) != elemType {
+			mixed = true
+			continue
+		}
+		out.Index(i).Set(reflect.ValueOf(item))
+		found = true
+	}
+	if mixed || !found {
+		return lst
+	}
+	return out.Interface()
+}
+
+//ConvertToJavaType is a function which converts a go value into the form it is encoded in as java type descriptor,
+//such as an int32 into an AtomicInteger. Values of other types are returned unchanged
+func ConvertToJavaType(javaType string, v interface{}) interface{} {
+	if convert, ok := javaValueConverters[javaType]; ok && v != nil {
+		return convert(v)
+	}
+	return v
+}
+
+//JavaTypeOf is a function which returns the java type descriptor of a go value
+func JavaTypeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return JavaString
+	case int32:
+		return JavaInteger
+	case int64:
+		return JavaLong
+	case float64:
+		return JavaDouble
+	case bool:
+		return JavaBoolean
+	case []bool:
+		return JavaBoolArray
+	case []*bool:
+		return JavaBooleanArray
+	case []interface{}:
+		return JavaList
+	}
+	if javaType, ok := javaTypesOf[reflect.TypeOf(v)]; ok {
+		return javaType
+	}
+	return JavaObject
+}
+
+//javaTypesOf maps the go forms of java classes to their java type descriptors
+var javaTypesOf = map[reflect.Type]string{
+	reflect.TypeOf(time.Time{}):                JavaZonedDateTime,
+	reflect.TypeOf(Locale("")):                 JavaLocale,
+	reflect.TypeOf(Currency("")):               JavaCurrency,
+	reflect.TypeOf(Pattern{}):                  JavaPattern,
+	reflect.TypeOf(net.IP{}):                   JavaInetAddress,
+	reflect.TypeOf(&url.URL{}):                 JavaURL,
+	reflect.TypeOf([]JavaEnum{}):               JavaEnumSet,
+	reflect.TypeOf(map[JavaEnum]interface{}{}): JavaEnumMap,
+	reflect.TypeOf(time.Duration(0)):           JavaDuration,
+	reflect.TypeOf(Period{}):                   JavaPeriod,
+	reflect.TypeOf(BitSet{}):                   JavaBitSet,
+	reflect.TypeOf(OrderedSet{}):               JavaSet,
+}
+
+//toHessianValue converts go values which hessian encoder does not support
+func toHessianValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []*bool:
+		lst := make([]interface{}, len(val))
+		for i, b := range val {
+			if b != nil {
+				lst[i] = *b
+			}
+		}
+		return lst
+	case time.Time:
+		return toZonedDateTimeHandle(val)
+	case time.Duration:
+		return toDurationHandle(val)
+	case Period:
+		return periodHandle(val)
+	case Locale:
+		return localeHandle{strings.Replace(string(val), "-", "_", -1)}
+	case Currency:
+		return currencyHandle{string(val)}
+	case Pattern:
+		return patternHandle(val)
+	case *url.URL:
+		return toURLHandle(val)
+	}
+	return derefObjects(v)
+}
+
+//derefObjects converts the decoded objects of registered java classes, which are pointers to structs, and the
+//slices of them into structs and lists of structs, nil pointers are null elements
+func derefObjects(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		return rv.Elem().Interface()
+	}
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Ptr || rv.Type().Elem().Elem().Kind() != reflect.Struct {
+		return v
+	}
+	lst := make([]interface{}, rv.Len())
+	for i := range lst {
+		if elem := rv.Index(i); !elem.IsNil() {
+			lst[i] = elem.Elem().Interface()
+		}
+	}
+	return lst
+}
+
+func toBoolArray(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case []bool:
+		return val, nil
+	case []interface{}:
+		lst := make([]bool, len(val))
+		for i, item := range val {
+			b, ok := item.(bool)
+			if !ok {
+				return nil, &BaseError{"boolean[] has non boolean element"}
+			}
+			lst[i] = b
+		}
+		return lst, nil
+	}
+	return nil, &BaseError{"boolean[] is not a list"}
+}
+
+func toBoxedBoolArray(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case []*bool:
+		return val, nil
+	case []interface{}:
+		lst := make([]*bool, len(val))
+		for i, item := range val {
+			if item == nil {
+				continue
+			}
+			b, ok := item.(bool)
+			if !ok {
+				return nil, &BaseError{"Boolean[] has non boolean element"}
+			}
+			lst[i] = &b
+		}
+		return lst, nil
+	}
+	return nil, &BaseError{"Boolean[] is not a list"}
+}
+
+//stringValue reads the value field of a java object serialized as a single string
+func stringValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case map[string]interface{}:
+		s, ok := val["value"].(string)
+		return s, ok
+	}
+	return "", false
+}
+
+func toLocale(v interface{}) (interface{}, error) {
+	if l, ok := v.(Locale); ok {
+		return l, nil
+	}
+	s, ok := stringValue(v)
+	if !ok {
+		return nil, &BaseError{"Locale has no string value"}
+	}
+	return Locale(strings.Replace(s, "_", "-", -1)), nil
+}
+
+func toCurrency(v interface{}) (interface{}, error) {
+	if c, ok := v.(Currency); ok {
+		return c, nil
+	}
+	s, ok := stringValue(v)
+	if !ok {
+		return nil, &BaseError{"Currency has no string value"}
+	}
+	return Currency(s), nil
+}
+
+func toPattern(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case Pattern:
+		return val, nil
+	case map[string]interface{}:
+		pattern, ok := val["pattern"].(string)
+		if !ok {
+			return nil, &BaseError{"Pattern has no pattern string"}
+		}
+		flags, _ := val["flags"].(int32)
+		return Pattern{pattern, flags}, nil
+	}
+	return nil, &BaseError{"Pattern is not an object"}
+}
+
+//toStreamList converts a stream result, which services send as a snapshot of its elements,
+//into a list. The snapshot is either a list or an object of a stream class holding the list in its only field
+func toStreamList(v interface{}) (interface{}, error) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		if class, _ := obj[ClassKey].(string); !isStreamClass(class) {
+			return nil, &BaseError{"Stream has no elements"}
+		}
+		var fields []interface{}
+		for name, field := range obj {
+			if name != ClassKey {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 1 {
+			v = fields[0]
+		}
+	}
+	if lst, ok := v.([]interface{}); ok {
+		return lst, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, &BaseError{"Stream has no elements"}
+	}
+	lst := make([]interface{}, rv.Len())
+	for i := range lst {
+		lst[i] = rv.Index(i).Interface()
+	}
+	return lst, nil
+}
+
+//isStreamClass reports whether the class of a snapshot object is a stream, such as com.demo.OrderStream,
+//other objects which happen to have one field are not taken for snapshots
+func isStreamClass(class string) bool {
+	return strings.HasSuffix(class, "Stream")
+}
+
+//boxedTypes maps the descriptors of java primitives to their boxed types
+var boxedTypes = map[byte]string{
+	JvmBool:   JavaBoolean,
+	JvmByte:   JavaByte,
+	JvmChar:   JavaChar,
+	JvmDouble: JavaDouble,
+	JvmFloat:  JavaFloat,
+	JvmInt:    JavaInteger,
+	JvmLong:   JavaLong,
+	JvmShort:  JavaShort,
+}
+
+//isBoxingPair checks whether one descriptor is a primitive and the other is its boxed type
+func isBoxingPair(a, b string) bool {
+	if len(a) == 1 {
+		return boxedTypes[a[0]] == b
+	}
+	if len(b) == 1 {
+		return boxedTypes[b[0]] == a
+	}
+	return false
+}
+
+//CoerceArguments is a function which boxes or unboxes the arguments to the java types of expected descriptor.
+//Arguments which are not a primitive and boxed pair of the expected types are left unchanged, and so are null
+//boxed values expected as primitives, which java would fail to unbox
+func CoerceArguments(args []Argument, expectedDesc string) {
+	expected := TypeDesToArgsObjArry(expectedDesc)
+	if len(expected) != len(args) {
+		return
+	}
+	for i := range args {
+		want := expected[i].GetJavaType()
+		if !isBoxingPair(args[i].GetJavaType(), want) || args[i].GetValue() == nil && len(want) == 1 {
+			continue
+		}
+		args[i].SetJavaType(want)
+	}
+}
+
+//EstimateSize is a function which estimates the number of bytes v is encoded in
+func EstimateSize(v interface{}) int {
+	switch val := v.(type) {
+	case nil, bool:
+		return 1
+	case string:
+		return len(val) + 3
+	case int32:
+		return 5
+	case int64, float64:
+		return 9
+	case []interface{}:
+		size := 5
+		for _, item := range val {
+			size += EstimateSize(item)
+		}
+		return size
+	case map[string]string:
+		size := 2
+		for k, item := range val {
+			size += len(k) + len(item) + 6
+		}
+		return size
+	case map[string]interface{}:
+		size := 2
+		for k, item := range val {
+			size += len(k) + 3 + EstimateSize(item)
+		}
+		return size
+	}
+	return 64
+}
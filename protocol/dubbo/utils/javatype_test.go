@@ -0,0 +1,267 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertByJavaType_BoolArrays(t *testing.T) {
+	v, err := ConvertByJavaType(JavaBoolArray, []interface{}{true, false})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, v)
+
+	_, err = ConvertByJavaType(JavaBoolArray, []interface{}{true, nil})
+	assert.Error(t, err)
+
+	v, err = ConvertByJavaType(JavaBooleanArray, []interface{}{true, nil})
+	assert.NoError(t, err)
+	boxed := v.([]*bool)
+	assert.True(t, *boxed[0])
+	assert.Nil(t, boxed[1])
+
+	v, err = ConvertByJavaType(JavaString, "unchanged")
+	assert.NoError(t, err)
+	assert.Equal(t, "unchanged", v)
+}
+
+func TestGetJavaDesc_BoolArrays(t *testing.T) {
+	args := []Argument{{Value: []bool{true}}, {Value: []*bool{nil}}, {JavaType: JavaString, Value: "s"}}
+	assert.Equal(t, "[Z[Ljava/lang/Boolean;Ljava/lang/String;", GetJavaDesc(args))
+}
+
+func TestConvertByJavaType_ZonedTime(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	assert.NoError(t, err)
+	tm := time.Date(2018, time.July, 2, 6, 14, 29, 0, loc)
+	assert.Equal(t, JavaZonedDateTime, JavaTypeOf(tm))
+
+	for _, javaType := range []string{JavaCalendar, JavaZonedDateTime} {
+		var rbf ReadBuffer
+		rbf.SetBuffer(writeObjects(t, tm))
+		obj, err := rbf.ReadObject()
+		assert.NoError(t, err)
+		v, err := ConvertByJavaType(javaType, obj)
+		assert.NoError(t, err)
+		decoded := v.(time.Time)
+		assert.True(t, tm.Equal(decoded))
+		assert.Equal(t, "Asia/Shanghai", decoded.Location().String())
+	}
+
+	_, err = ConvertByJavaType(JavaCalendar, "2018-07-02")
+	assert.Error(t, err)
+}
+
+func TestConvertByJavaType_OffsetDateTime(t *testing.T) {
+	tm := time.Date(2019, time.March, 1, 9, 30, 0, 0, time.FixedZone("IST", 5*3600+30*60))
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, ConvertToJavaType(JavaOffsetDateTime, tm)))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err := ConvertByJavaType(JavaOffsetDateTime, obj)
+	assert.NoError(t, err)
+	decoded := v.(time.Time)
+	assert.True(t, tm.Equal(decoded))
+	name, offset := decoded.Zone()
+	assert.Equal(t, "+05:30", name)
+	assert.Equal(t, 5*3600+30*60, offset)
+	assert.Equal(t, 9, decoded.Hour())
+
+	assert.Equal(t, "-03:00", offsetZone(-3*3600).String())
+	assert.Equal(t, "Z", offsetZone(0).String())
+
+	//offsets take the three-byte form of ints
+	rbf.SetBuffer(writeObjects(t, map[string]interface{}{"seconds": int32(-19800)}))
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"seconds": int32(-19800)}, obj)
+}
+
+func TestConvertByJavaType_Instant(t *testing.T) {
+	instants := []time.Time{
+		time.Unix(1550000000, 123456).UTC(),
+		time.Unix(4102444800, 999999999).UTC(),
+		time.Unix(-1, 1).UTC(),
+	}
+	var rbf ReadBuffer
+	for _, tm := range instants {
+		rbf.SetBuffer(writeObjects(t, ConvertToJavaType(JavaInstant, tm)))
+		obj, err := rbf.ReadObject()
+		assert.NoError(t, err)
+		v, err := ConvertByJavaType(JavaInstant, obj)
+		assert.NoError(t, err)
+		assert.Equal(t, tm, v)
+	}
+	_, err := ConvertByJavaType(JavaInstant, int64(1550000000))
+	assert.Error(t, err)
+}
+
+func TestConvertByJavaType_DurationPeriod(t *testing.T) {
+	d := 90*time.Second + 500*time.Millisecond
+	p := Period{Years: 1, Months: 2, Days: 3}
+	assert.Equal(t, JavaDuration, JavaTypeOf(d))
+	assert.Equal(t, JavaPeriod, JavaTypeOf(p))
+
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, d, p))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err := ConvertByJavaType(JavaDuration, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, d, v)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err = ConvertByJavaType(JavaPeriod, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, p, v)
+
+	//a month from january 31st is not a fixed number of days
+	start := time.Date(2019, time.January, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2019, time.March, 3, 0, 0, 0, 0, time.UTC), Period{Months: 1}.AddTo(start))
+	assert.Equal(t, durationHandle{-2, 500000000}, toDurationHandle(-1500*time.Millisecond))
+
+	_, err = ConvertByJavaType(JavaPeriod, "P1Y")
+	assert.Error(t, err)
+}
+
+func TestConvertByJavaType_Money(t *testing.T) {
+	RegisterMoneyType(JodaMoney)
+	RegisterMoneyType(MonetaMoney)
+	defer unregisterMoneyType(JodaMoney)
+	defer unregisterMoneyType(MonetaMoney)
+	m := Money{Amount: "12345678901234567890.123456789", Currency: "EUR"}
+	jodaMoney := "Lorg/joda/money/Money;"
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, ConvertToJavaType(jodaMoney, m), ConvertToJavaType("Lorg/javamoney/moneta/Money;", m)))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890.123456789", pathValue(obj.(map[string]interface{}), "money.amount.value"))
+	v, err := ConvertByJavaType(jodaMoney, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, m, v)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err = ConvertByJavaType("Lorg/javamoney/moneta/Money;", obj)
+	assert.NoError(t, err)
+	assert.Equal(t, m, v)
+
+	_, err = ConvertByJavaType(jodaMoney, map[string]interface{}{"money": nil})
+	assert.Error(t, err)
+}
+
+//unregisterMoneyType removes the converters RegisterMoneyType added for the class of t
+func unregisterMoneyType(t *MoneyType) {
+	desc := "L" + strings.Replace(t.Class, ".", "/", -1) + ";"
+	delete(javaTypeConverters, desc)
+	delete(javaValueConverters, desc)
+}
+
+func TestConvertByJavaType_Adders(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, ConvertToJavaType(JavaLongAdder, int64(1024)),
+		ConvertToJavaType(JavaDoubleAdder, 2.75), ConvertToJavaType(JavaDoubleAdder, float64(3))))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, atomicPackage+"LongAdder$SerializationProxy", obj.(map[string]interface{})[ClassKey])
+	v, err := ConvertByJavaType(JavaLongAdder, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), v)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err = ConvertByJavaType(JavaDoubleAdder, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.75, v)
+	obj, err = rbf.ReadObject()
+	assert.NoError(t, err)
+	v, err = ConvertByJavaType(JavaDoubleAdder, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), v)
+
+	_, err = ConvertByJavaType(JavaLongAdder, map[string]interface{}{"value": "1"})
+	assert.Error(t, err)
+}
+
+func TestConvertByJavaType_Stream(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer(writeObjects(t, unknownItem{"a-1"}))
+	obj, err := rbf.ReadObject()
+	assert.NoError(t, err)
+	_, err = ConvertByJavaType(JavaStream, obj)
+	assert.Error(t, err)
+
+	v, err := ConvertByJavaType(JavaStream, []string{"x"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"x"}, v)
+	_, err = ConvertByJavaType(JavaStream, "x")
+	assert.Error(t, err)
+}
+
+func TestCoerceArguments(t *testing.T) {
+	args := []Argument{
+		{JavaType: "I", Value: int32(5)},
+		{JavaType: JavaLong, Value: int64(100)},
+		{JavaType: JavaInteger, Value: nil},
+		{JavaType: JavaString, Value: "s"},
+	}
+	CoerceArguments(args, "Ljava/lang/Integer;JILjava/lang/Object;")
+	assert.Equal(t, Argument{JavaType: JavaInteger, Value: int32(5)}, args[0])
+	assert.Equal(t, Argument{JavaType: "J", Value: int64(100)}, args[1])
+	assert.Equal(t, Argument{JavaType: JavaInteger, Value: nil}, args[2])
+	assert.Equal(t, Argument{JavaType: JavaString, Value: "s"}, args[3])
+
+	CoerceArguments(args, "J")
+	assert.Equal(t, JavaInteger, args[0].GetJavaType())
+}
+
+func TestCoerceJSONArguments(t *testing.T) {
+	cases := []struct {
+		desc  string
+		value float64
+		want  interface{}
+	}{
+		{"I", 42, int32(42)},
+		{JavaInteger, -7, int32(-7)},
+		{"J", 1 << 40, int64(1 << 40)},
+		{JavaLong, -1 << 53, int64(-1 << 53)},
+		{"S", 32767, int32(32767)},
+		{JavaShort, -32768, int32(-32768)},
+		{"B", 127, int32(127)},
+		{JavaByte, -128, int32(-128)},
+		{"D", 1.5, float64(1.5)},
+		{JavaFloat, 2.25, float64(2.25)},
+	}
+	for _, c := range cases {
+		args := []Argument{{Value: c.value}, {Value: "unchanged"}}
+		assert.NoError(t, CoerceJSONArguments(args, c.desc+JavaString), c.desc)
+		assert.Equal(t, c.want, args[0].GetValue(), c.desc)
+		assert.Equal(t, "unchanged", args[1].GetValue())
+	}
+
+	for _, c := range []struct {
+		desc  string
+		value float64
+	}{{"I", 1.5}, {"I", 1 << 31}, {"S", 32768}, {"B", -129}, {"J", 1 << 63}} {
+		err := CoerceJSONArguments([]Argument{{Value: c.value}}, c.desc)
+		assert.Equal(t, ErrLossyNumber, err, c.desc)
+	}
+	assert.Error(t, CoerceJSONArguments([]Argument{{Value: float64(1)}}, "II"))
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"math"
+)
+
+//ErrLossyNumber is returned when a json number can not be converted to the expected java type without loss
+var ErrLossyNumber = &BaseError{"json number does not fit the expected java type"}
+
+//integerRange is the range of an integral java type, max is excluded so that it is exact as a float64
+type integerRange struct {
+	min, max float64
+	long     bool
+}
+
+//jsonIntegerRanges holds the integral java types which json numbers are converted to
+var jsonIntegerRanges = map[string]integerRange{
+	"B":         {math.MinInt8, math.MaxInt8 + 1, false},
+	JavaByte:    {math.MinInt8, math.MaxInt8 + 1, false},
+	"S":         {math.MinInt16, math.MaxInt16 + 1, false},
+	JavaShort:   {math.MinInt16, math.MaxInt16 + 1, false},
+	"I":         {math.MinInt32, math.MaxInt32 + 1, false},
+	JavaInteger: {math.MinInt32, math.MaxInt32 + 1, false},
+	"J":         {math.MinInt64, -math.MinInt64, true},
+	JavaLong:    {math.MinInt64, -math.MinInt64, true},
+}
+
+//CoerceJSONArguments is a function which converts the float64 numbers of arguments decoded from json into the
+//integral types of expected descriptor, int64 for longs and int32 for the smaller ones as the decoder does.
+//A number with a fraction or out of the range of its type fails with ErrLossyNumber
+func CoerceJSONArguments(args []Argument, expectedDesc string) error {
+	expected := TypeDesToArgsObjArry(expectedDesc)
+	if len(expected) != len(args) {
+		return &BaseError{"arguments do not match the expected descriptor"}
+	}
+	for i := range args {
+		want := expected[i].GetJavaType()
+		f, ok := args[i].GetValue().(float64)
+		r, integral := jsonIntegerRanges[want]
+		if !ok || !integral {
+			continue
+		}
+		if f != math.Trunc(f) || f < r.min || f >= r.max {
+			return ErrLossyNumber
+		}
+		if r.long {
+			args[i].SetValue(int64(f))
+		} else {
+			args[i].SetValue(int32(f))
+		}
+		args[i].SetJavaType(want)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/go-chassis/gohessian"
+)
+
+//isTypedList reports whether tag starts a typed list, which java writes for arrays such as Object[]
+func isTypedList(tag byte) bool {
+	return tag == hessian.BC_LIST_FIXED || tag == hessian.BC_LIST_VARIABLE ||
+		(tag >= hessian.BC_LIST_DIRECT && tag < hessian.BC_LIST_DIRECT_UNTYPED)
+}
+
+//listUntyper copies a hessian2 value with its lists rewritten as untyped fixed lists and its maps as untyped
+//maps. gohessian can not resolve the type references java writes for repeated list types, nor read lists of
+//variable length, and it reads only the first entry of typed maps
+type listUntyper struct {
+	classWalker
+	out []byte
+}
+
+//copyValue copies the next value
+func (u *listUntyper) copyValue() error {
+	start := u.pos
+	tag, err := u.next()
+	if err != nil {
+		return err
+	}
+	switch tagClasses[tag] {
+	case tagList:
+		return u.copyList(tag)
+	case tagClassDef:
+		if err := u.readClassDef(); err != nil {
+			return err
+		}
+		u.out = append(u.out, u.buf[start:u.pos]...)
+		return u.copyValue()
+	case tagInstance:
+		return u.copyInstance(tag, start)
+	case tagMap:
+		return u.copyMap(tag, start)
+	}
+	u.pos = start
+	if err := u.scanValue(0); err != nil {
+		return err
+	}
+	u.out = append(u.out, u.buf[start:u.pos]...)
+	return nil
+}
+
+func (u *listUntyper) copyInstance(tag byte, start int) error {
+	idx := int(tag - hessian.BC_OBJECT_DIRECT)
+	if tag == hessian.BC_OBJECT {
+		var err error
+		if idx, err = u.scanInt(); err != nil {
+			return err
+		}
+	}
+	if idx < 0 || idx >= len(u.defs) {
+		return ErrUnknownTag
+	}
+	u.out = append(u.out, u.buf[start:u.pos]...)
+	for range u.defs[idx].fields {
+		if err := u.copyValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *listUntyper) copyMap(tag byte, start int) error {
+	if tag == hessian.BC_MAP {
+		class, err := u.readType()
+		if err != nil {
+			return err
+		}
+		if isGuavaImmutable(class) {
+			return u.copyImmutableMap()
+		}
+		start = u.pos
+		u.out = append(u.out, hessian.BC_MAP_UNTYPED)
+	}
+	u.out = append(u.out, u.buf[start:u.pos]...)
+	for {
+		if tag, err := u.peek(); err != nil || tag == hessian.BC_END {
+			u.pos++
+			u.out = append(u.out, hessian.BC_END)
+			return err
+		}
+		if err := u.copyValue(); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *listUntyper) copyList(tag byte) error {
+	size, err := u.listSize(tag)
+	if err != nil {
+		return err
+	}
+	head := len(u.out)
+	count := 0
+	for ; count != size; count++ {
+		if size < 0 {
+			tag, err := u.peek()
+			if err != nil {
+				return err
+			}
+			if tag == hessian.BC_END {
+				u.pos++
+				break
+			}
+		}
+		if err := u.copyValue(); err != nil {
+			return err
+		}
+	}
+	elems := append([]byte(nil), u.out[head:]...)
+	u.out = u.out[:head]
+	if count < 8 {
+		u.out = append(u.out, hessian.BC_LIST_DIRECT_UNTYPED+byte(count))
+	} else {
+		u.out = append(u.out, hessian.BC_LIST_FIXED_UNTYPED, hessian.BC_INT, 0, 0, 0, 0)
+		Int2bytes(count, u.out, len(u.out)-4)
+	}
+	u.out = append(u.out, elems...)
+	return nil
+}
+
+//readTypedList reads a typed list, such as the Object[] of a batch method result, into a slice. It also
+//reads the guava immutable maps, which are typed maps, into maps
+func (b *ReadBuffer) readTypedList() (interface{}, error) {
+	u := &listUntyper{classWalker: classWalker{scanner: scanner{buf: b.buffer[b.rdInd:b.length]}}}
+	if err := u.copyValue(); err != nil {
+		return nil, err
+	}
+	b.rdInd += u.pos
+	var untyped ReadBuffer
+	untyped.SetBuffer(u.out)
+	return untyped.readHessian()
+}
@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"sync"
+)
+
+//size classes of pooled read buffers, from 512 bytes to 8MB
+const (
+	minPooledShift = 9
+	maxPooledShift = 23
+)
+
+var readBufferPools [maxPooledShift - minPooledShift + 1]sync.Pool
+
+//sizeClass returns the index of the smallest pool holding n bytes, -1 if n is too large to be pooled
+func sizeClass(n int) int {
+	for shift := minPooledShift; shift <= maxPooledShift; shift++ {
+		if n <= 1<<uint(shift) {
+			return shift - minPooledShift
+		}
+	}
+	return -1
+}
+
+//GetReadBuffer is a function which returns a read buffer holding n bytes from a size-classed pool,
+//the buffer should be returned by PutReadBuffer once the objects are read
+func GetReadBuffer(n int) *ReadBuffer {
+	class := sizeClass(n)
+	var b *ReadBuffer
+	if class < 0 {
+		b = &ReadBuffer{buffer: make([]byte, n)}
+	} else if v := readBufferPools[class].Get(); v != nil {
+		b = v.(*ReadBuffer)
+	} else {
+		b = &ReadBuffer{buffer: make([]byte, 1<<uint(class+minPooledShift))}
+	}
+	b.buffer = b.buffer[:cap(b.buffer)]
+	b.capacity = len(b.buffer)
+	b.length = n
+	b.rdInd = 0
+	b.drained = false
+	b.overread = 0
+	b.java = nil
+	b.maxDepth = 0
+	b.maxChars = 0
+	b.interner = nil
+	return b
+}
+
+//PutReadBuffer is a function which returns a buffer got from GetReadBuffer to its pool
+func PutReadBuffer(b *ReadBuffer) {
+	class := sizeClass(cap(b.buffer))
+	if class < 0 || cap(b.buffer) != 1<<uint(class+minPooledShift) {
+		return
+	}
+	readBufferPools[class].Put(b)
+}
@@ -0,0 +1,63 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReadBuffer(t *testing.T) {
+	data := writeObjects(t, "hello")
+	b := GetReadBuffer(len(data))
+	assert.Len(t, b.Bytes(), len(data))
+	assert.Equal(t, 512, cap(b.Bytes()))
+	copy(b.Bytes(), data)
+	obj, err := b.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", obj)
+	PutReadBuffer(b)
+
+	b = GetReadBuffer(1000)
+	assert.Len(t, b.Bytes(), 1000)
+	assert.Equal(t, 1024, cap(b.Bytes()))
+	PutReadBuffer(b)
+
+	huge := GetReadBuffer(16 << 20)
+	assert.Len(t, huge.Bytes(), 16<<20)
+	PutReadBuffer(huge)
+}
+
+var benchmarkBodySizes = []int{100, 700, 3000, 20000, 120000}
+
+func BenchmarkReadBuffer_Alloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buffer ReadBuffer
+		buffer.SetBuffer(make([]byte, benchmarkBodySizes[i%len(benchmarkBodySizes)]))
+	}
+}
+
+func BenchmarkReadBuffer_Pooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buffer := GetReadBuffer(benchmarkBodySizes[i%len(benchmarkBodySizes)])
+		PutReadBuffer(buffer)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/go-chassis/gohessian"
+)
+
+var (
+	//ErrMaxDepthExceeded is returned when a value is nested deeper than the configured max depth
+	ErrMaxDepthExceeded = &BaseError{"hessian value exceeds max nesting depth"}
+	//ErrTruncatedValue is returned when the buffer ends in the middle of a value
+	ErrTruncatedValue = &BaseError{"hessian value is truncated"}
+	//ErrUnknownTag is returned when the scanner meets a tag it does not understand
+	ErrUnknownTag = &BaseError{"hessian value has unknown tag"}
+	//ErrValueTooLarge is returned when a value is larger than the size limit of scanner
+	ErrValueTooLarge = &BaseError{"hessian value exceeds max size"}
+)
+
+//scanner walks a hessian2 value without materializing it
+type scanner struct {
+	buf      []byte
+	pos      int
+	maxDepth int
+	maxSize  int   //limit of the scanned bytes, 0 means no limit
+	clsDefs  []int //field count of every class definition
+}
+
+func newScanner(buf []byte, maxDepth int) *scanner {
+	return &scanner{buf: buf, maxDepth: maxDepth}
+}
+
+func (s *scanner) next() (byte, error) {
+	if s.pos >= len(s.buf) {
+		return 0, ErrTruncatedValue
+	}
+	tag := s.buf[s.pos]
+	s.pos++
+	return tag, nil
+}
+
+func (s *scanner) skip(n int) error {
+	if s.maxSize > 0 && s.pos+n > s.maxSize {
+		return ErrValueTooLarge
+	}
+	if n < 0 || s.pos+n > len(s.buf) {
+		return ErrTruncatedValue
+	}
+	s.pos += n
+	return nil
+}
+
+func (s *scanner) peek() (byte, error) {
+	if s.pos >= len(s.buf) {
+		return 0, ErrTruncatedValue
+	}
+	return s.buf[s.pos], nil
+}
+
+//classes of hessian2 tags
+const (
+	tagUnknown = iota
+	tagFixed
+	tagChunked
+	tagRef
+	tagClassDef
+	tagInstance
+	tagMap
+	tagList
+)
+
+var (
+	tagClasses [256]byte
+	tagSizes   [256]int //bytes following a tagFixed tag
+)
+
+func setTags(class byte, size int, first, last byte) {
+	for tag := int(first); tag <= int(last); tag++ {
+		tagClasses[tag] = class
+		tagSizes[tag] = size
+	}
+}
+
+func init() {
+	for _, tag := range []byte{hessian.BC_NULL, hessian.BC_TRUE, hessian.BC_FALSE,
+		hessian.BC_DOUBLE_ZERO, hessian.BC_DOUBLE_ONE} {
+		setTags(tagFixed, 0, tag, tag)
+	}
+	setTags(tagFixed, 0, 0x80, 0xbf)
+	setTags(tagFixed, 0, 0xd8, 0xef)
+	setTags(tagFixed, 1, 0xc0, 0xcf)
+	setTags(tagFixed, 1, 0xf0, 0xff)
+	setTags(tagFixed, 1, hessian.BC_DOUBLE_BYTE, hessian.BC_DOUBLE_BYTE)
+	setTags(tagFixed, 2, 0xd0, 0xd7)
+	setTags(tagFixed, 2, 0x38, 0x3f)
+	setTags(tagFixed, 2, hessian.BC_DOUBLE_SHORT, hessian.BC_DOUBLE_SHORT)
+	for _, tag := range []byte{hessian.BC_INT, hessian.BC_LONG_INT, hessian.BC_DOUBLE_MILL, hessian.BC_DATE_MINUTE} {
+		setTags(tagFixed, 4, tag, tag)
+	}
+	for _, tag := range []byte{hessian.BC_LONG, hessian.BC_DOUBLE, hessian.BC_DATE} {
+		setTags(tagFixed, 8, tag, tag)
+	}
+	setTags(tagChunked, 0, 0x00, 0x37)
+	for _, tag := range []byte{hessian.BC_STRING, hessian.BC_STRING_CHUNK, hessian.BC_BINARY, hessian.BC_BINARY_CHUNK} {
+		setTags(tagChunked, 0, tag, tag)
+	}
+	setTags(tagRef, 0, hessian.BC_REF, hessian.BC_REF)
+	setTags(tagClassDef, 0, hessian.BC_OBJECT_DEF, hessian.BC_OBJECT_DEF)
+	setTags(tagInstance, 0, hessian.BC_OBJECT, hessian.BC_OBJECT)
+	setTags(tagInstance, 0, hessian.BC_OBJECT_DIRECT, 0x6f)
+	setTags(tagMap, 0, hessian.BC_MAP, hessian.BC_MAP)
+	setTags(tagMap, 0, hessian.BC_MAP_UNTYPED, hessian.BC_MAP_UNTYPED)
+	setTags(tagList, 0, hessian.BC_LIST_DIRECT, 0x7f)
+	for _, tag := range []byte{hessian.BC_LIST_FIXED, hessian.BC_LIST_VARIABLE,
+		hessian.BC_LIST_FIXED_UNTYPED, hessian.BC_LIST_VARIABLE_UNTYPED} {
+		setTags(tagList, 0, tag, tag)
+	}
+}
+
+//scanValue skips one value, depth is the nesting level of its container
+func (s *scanner) scanValue(depth int) error {
+	tag, err := s.next()
+	if err != nil {
+		return err
+	}
+	switch tagClasses[tag] {
+	case tagFixed:
+		return s.skip(tagSizes[tag])
+	case tagChunked:
+		return s.scanChunked(tag)
+	case tagRef:
+		_, err := s.scanInt()
+		return err
+	case tagClassDef:
+		if err := s.scanClassDef(); err != nil {
+			return err
+		}
+		return s.scanValue(depth)
+	case tagInstance:
+		return s.scanInstance(tag, depth+1)
+	case tagMap:
+		return s.scanMap(tag, depth+1)
+	case tagList:
+		return s.scanList(tag, depth+1)
+	}
+	return ErrUnknownTag
+}
+
+//scanChunked skips a string or binary value which may be split into chunks
+func (s *scanner) scanChunked(tag byte) error {
+	for {
+		n, err := s.chunkLength(tag)
+		if err != nil {
+			return err
+		}
+		if err := s.skip(n); err != nil {
+			return err
+		}
+		if tag != hessian.BC_STRING_CHUNK && tag != hessian.BC_BINARY_CHUNK {
+			return nil
+		}
+		if tag, err = s.next(); err != nil {
+			return err
+		}
+	}
+}
+
+//chunkLength reads the length of a string or binary chunk
+func (s *scanner) chunkLength(tag byte) (int, error) {
+	switch {
+	case tag <= hessian.STRING_DIRECT_MAX:
+		return int(tag), nil
+	case tag >= hessian.BC_BINARY_DIRECT && tag <= 0x2f:
+		return int(tag - hessian.BC_BINARY_DIRECT), nil
+	case tag >= 0x30 && tag <= 0x37:
+		b, err := s.next()
+		return int(tag&0x03)<<8 + int(b), err
+	case tag == hessian.BC_STRING, tag == hessian.BC_STRING_CHUNK,
+		tag == hessian.BC_BINARY, tag == hessian.BC_BINARY_CHUNK:
+		if err := s.skip(2); err != nil {
+			return 0, err
+		}
+		return int(s.buf[s.pos-2])<<8 + int(s.buf[s.pos-1]), nil
+	}
+	return 0, ErrUnknownTag
+}
+
+//scanInt reads an integer which is used as a length or an index
+func (s *scanner) scanInt() (int, error) {
+	tag, err := s.next()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case tag >= 0x80 && tag <= 0xbf:
+		return int(tag) - int(hessian.BC_INT_ZERO), nil
+	case tag >= 0xc0 && tag <= 0xcf:
+		b, err := s.next()
+		return (int(tag)-int(hessian.BC_INT_BYTE_ZERO))<<8 + int(b), err
+	case tag >= 0xd0 && tag <= 0xd7:
+		if err := s.skip(2); err != nil {
+			return 0, err
+		}
+		return (int(tag)-int(hessian.BC_INT_SHORT_ZERO))<<16 + int(s.buf[s.pos-2])<<8 + int(s.buf[s.pos-1]), nil
+	case tag == hessian.BC_INT:
+		if err := s.skip(4); err != nil {
+			return 0, err
+		}
+		return int(Bytes2int(s.buf, s.pos-4)), nil
+	}
+	return 0, ErrUnknownTag
+}
+
+func (s *scanner) scanClassDef() error {
+	if err := s.scanValue(0); err != nil { //class name
+		return err
+	}
+	count, err := s.scanInt()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		if err := s.scanValue(0); err != nil { //field name
+			return err
+		}
+	}
+	s.clsDefs = append(s.clsDefs, count)
+	return nil
+}
+
+func (s *scanner) checkDepth(depth int) error {
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return ErrMaxDepthExceeded
+	}
+	return nil
+}
+
+func (s *scanner) scanInstance(tag byte, depth int) error {
+	if err := s.checkDepth(depth); err != nil {
+		return err
+	}
+	idx := int(tag - hessian.BC_OBJECT_DIRECT)
+	if tag == hessian.BC_OBJECT {
+		var err error
+		if idx, err = s.scanInt(); err != nil {
+			return err
+		}
+	}
+	if idx < 0 || idx >= len(s.clsDefs) {
+		return ErrUnknownTag
+	}
+	for i := 0; i < s.clsDefs[idx]; i++ {
+		if err := s.scanValue(depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//scanUntilEnd skips values until the end marker of a map or a variable list
+func (s *scanner) scanUntilEnd(depth int) error {
+	for {
+		tag, err := s.peek()
+		if err != nil {
+			return err
+		}
+		if tag == hessian.BC_END {
+			s.pos++
+			return nil
+		}
+		if err := s.scanValue(depth); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *scanner) scanMap(tag byte, depth int) error {
+	if err := s.checkDepth(depth); err != nil {
+		return err
+	}
+	if tag == hessian.BC_MAP {
+		if err := s.scanValue(0); err != nil { //type
+			return err
+		}
+	}
+	return s.scanUntilEnd(depth)
+}
+
+func (s *scanner) scanList(tag byte, depth int) error {
+	if err := s.checkDepth(depth); err != nil {
+		return err
+	}
+	typed := tag == hessian.BC_LIST_FIXED || tag == hessian.BC_LIST_VARIABLE ||
+		(tag >= hessian.BC_LIST_DIRECT && tag < hessian.BC_LIST_DIRECT_UNTYPED)
+	if typed {
+		if err := s.scanValue(0); err != nil { //type
+			return err
+		}
+	}
+	if tag == hessian.BC_LIST_VARIABLE || tag == hessian.BC_LIST_VARIABLE_UNTYPED {
+		return s.scanUntilEnd(depth)
+	}
+	var size int
+	switch {
+	case tag >= hessian.BC_LIST_DIRECT_UNTYPED:
+		size = int(tag - hessian.BC_LIST_DIRECT_UNTYPED)
+	case tag >= hessian.BC_LIST_DIRECT:
+		size = int(tag - hessian.BC_LIST_DIRECT)
+	default:
+		var err error
+		if size, err = s.scanInt(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < size; i++ {
+		if err := s.scanValue(depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"sync"
+)
+
+//Serializer is an interface which reads and writes values of a serialization format
+type Serializer interface {
+	ReadObject(b *ReadBuffer) (interface{}, error)
+	WriteObject(b *WriteBuffer, v interface{}) error
+}
+
+//BodySerializer is an interface which is implemented by the serializers able to read whole dubbo bodies,
+//the strings heading requests, the flag of responses and the attachments besides the values
+type BodySerializer interface {
+	Serializer
+	ReadUTF(b *ReadBuffer) (string, error)
+	ReadFlag(b *ReadBuffer) (byte, error)
+	ReadMap(b *ReadBuffer) (map[string]string, error)
+}
+
+//serializers holds the registered serializers by serialization id
+var serializers = make(map[byte]Serializer)
+
+//argSerializations holds the serialization ids of arguments by java type descriptor
+var argSerializations = make(map[string]byte)
+
+//serializersMtx guards serializers and argSerializations, which are registered while connections decode
+var serializersMtx sync.RWMutex
+
+//RegisterSerializer is a function which registers a serializer for a serialization id, nil removes the serializer
+func RegisterSerializer(id byte, s Serializer) {
+	serializersMtx.Lock()
+	if s == nil {
+		delete(serializers, id)
+	} else {
+		serializers[id] = s
+	}
+	serializersMtx.Unlock()
+}
+
+//GetSerializer is a function which returns the serializer of a serialization id
+func GetSerializer(id byte) (Serializer, bool) {
+	serializersMtx.RLock()
+	s, ok := serializers[id]
+	serializersMtx.RUnlock()
+	return s, ok
+}
+
+//RegisterArgumentSerialization is a function which makes arguments of a java type use another serialization,
+//0 makes them use the serialization of the connection again
+func RegisterArgumentSerialization(javaType string, id byte) {
+	serializersMtx.Lock()
+	if id == 0 {
+		delete(argSerializations, javaType)
+	} else {
+		argSerializations[javaType] = id
+	}
+	serializersMtx.Unlock()
+}
+
+//argumentSerialization returns the serialization id registered for the arguments of a java type
+func argumentSerialization(javaType string) byte {
+	serializersMtx.RLock()
+	id := argSerializations[javaType]
+	serializersMtx.RUnlock()
+	return id
+}
+
+//Hessian2Serializer is a struct which implements BodySerializer with hessian2
+type Hessian2Serializer struct{}
+
+//ReadObject is a method to read a hessian2 object from buffer
+func (Hessian2Serializer) ReadObject(b *ReadBuffer) (interface{}, error) {
+	return b.ReadObject()
+}
+
+//WriteObject is a method to write a hessian2 object into buffer
+func (Hessian2Serializer) WriteObject(b *WriteBuffer, v interface{}) error {
+	return b.WriteObject(v)
+}
+
+//ReadUTF is a method to read a hessian2 string from buffer, null is read as an empty string
+func (Hessian2Serializer) ReadUTF(b *ReadBuffer) (string, error) {
+	return b.ReadStringE()
+}
+
+//ReadFlag is a method to read a byte written as a hessian2 int from buffer
+func (Hessian2Serializer) ReadFlag(b *ReadBuffer) (byte, error) {
+	return b.ReadByteE()
+}
+
+//ReadMap is a method to read a hessian2 map from buffer
+func (Hessian2Serializer) ReadMap(b *ReadBuffer) (map[string]string, error) {
+	return b.ReadMap()
+}
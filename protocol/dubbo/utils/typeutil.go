@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"math"
 	"net/url"
+	"reflect"
 	"regexp"
 )
 
@@ -40,7 +41,31 @@ const (
 	JavaObject  = "Ljava/lang/Object;"
 	JavaList    = "Ljava/util/List;"
 	JavaMap     = "Ljava.util.Map;"
+	JavaSet     = "Ljava/util/Set;"
 	JavaSplit   = ";"
+
+	JavaBoolArray    = "[Z"
+	JavaBooleanArray = "[Ljava/lang/Boolean;"
+
+	JavaCalendar       = "Ljava/util/Calendar;"
+	JavaZonedDateTime  = "Ljava/time/ZonedDateTime;"
+	JavaOffsetDateTime = "Ljava/time/OffsetDateTime;"
+	JavaLocale         = "Ljava/util/Locale;"
+	JavaCurrency       = "Ljava/util/Currency;"
+	JavaStream         = "Ljava/util/stream/Stream;"
+	JavaPattern        = "Ljava/util/regex/Pattern;"
+	JavaInetAddress    = "Ljava/net/InetAddress;"
+	JavaURL            = "Ljava/net/URL;"
+	JavaEnumSet        = "Ljava/util/EnumSet;"
+	JavaEnumMap        = "Ljava/util/EnumMap;"
+	JavaAtomicInteger  = "Ljava/util/concurrent/atomic/AtomicInteger;"
+	JavaAtomicLong     = "Ljava/util/concurrent/atomic/AtomicLong;"
+	JavaLongAdder      = "Ljava/util/concurrent/atomic/LongAdder;"
+	JavaDoubleAdder    = "Ljava/util/concurrent/atomic/DoubleAdder;"
+	JavaDuration       = "Ljava/time/Duration;"
+	JavaPeriod         = "Ljava/time/Period;"
+	JavaInstant        = "Ljava/time/Instant;"
+	JavaBitSet         = "Ljava/util/BitSet;"
 )
 
 //Constants ..
@@ -164,6 +189,34 @@ func ObjectToString(dtype string, v interface{}) (string, error) {
 	return "", &BaseError{"Unsurported Type"}
 }
 
+//ToJSONValue is a function which converts a decoded value into one accepted by encoding/json
+func ToJSONValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = ToJSONValue(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = ToJSONValue(item)
+		}
+		return m
+	case []interface{}:
+		lst := make([]interface{}, len(val))
+		for i, item := range val {
+			lst[i] = ToJSONValue(item)
+		}
+		return lst
+	case reflect.Value: //registered java classes are decoded as reflect value
+		return ToJSONValue(val.Interface())
+	default:
+		return v
+	}
+}
+
 //RestBytesToLstValue is a function
 func RestBytesToLstValue(jType string, value [][]byte) (interface{}, error) {
 	var tmp []interface{}
@@ -229,7 +282,11 @@ BYTE  SHORT  INTEGER 当做整形处理
 func GetJavaDesc(args []Argument) string {
 	tmpDesc := ""
 	for _, tmp := range args {
-		tmpDesc += tmp.GetJavaType()
+		jType := tmp.GetJavaType()
+		if jType == "" {
+			jType = JavaTypeOf(tmp.GetValue())
+		}
+		tmpDesc += jType
 	}
 	return tmpDesc
 }
@@ -245,7 +302,7 @@ func TypeDesToArgsObjArry(desc string) []Argument {
 
 	var i = 0
 	for _, match := range reg.FindAll(descBytes, -1) {
-		tmpArgsAarry[i] = Argument{string(match[:]), nil}
+		tmpArgsAarry[i] = Argument{JavaType: string(match[:])}
 		i++
 	}
 
@@ -262,6 +319,16 @@ func TypeDesToArgsObjArry(desc string) []Argument {
 type Argument struct {
 	JavaType string
 	Value    interface{}
+	//Serialization overrides the serialization of connection for this argument, 0 means no override
+	Serialization byte
+}
+
+//GetSerialization is a method which returns the serialization hint of argument
+func (p *Argument) GetSerialization() byte {
+	if p.Serialization != 0 {
+		return p.Serialization
+	}
+	return argumentSerialization(p.JavaType)
 }
 
 //SetJavaType is method which sets javatype